@@ -7,27 +7,49 @@ package bootstrap
 
 import (
 	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"sort"
+	"strings"
+
+	"github.com/kiwicom/easycql/parser"
 )
 
 // package paths to use in generated files.
 const (
 	genPackage = "github.com/kiwicom/easycql/gen"
 	pkgGocql   = "github.com/gocql/gocql"
+	pkgEasyCQL = "github.com/kiwicom/easycql"
 )
 
 type Generator struct {
 	PkgPath, PkgName string
 	Types            []string
 
+	// TableTypes maps types with CQL statements to whether they are counter tables, PresenceTypes are types
+	// with CQLFieldState method.
+	TableTypes    map[string]bool
+	PresenceTypes map[string]bool
+	// CounterFields maps types of counter tables to fields of their delta types.
+	CounterFields map[string][]string
+	// CollectionFields maps types of regular tables to fields with collection mutation methods, Imports are
+	// packages referred to by their types.
+	CollectionFields map[string][]parser.CollectionField
+	Imports          map[string]string
+
 	SnakeCase             bool
 	LowerCamelCase        bool
+	FieldNamer            string // importpath.TypeName of a custom gen.FieldNamer
 	DisallowUnknownFields bool
 	Conservative          bool
+	ResetOnDecode         bool
+	PointerMarshalers     bool
+	UDTMarshalers         bool
+	RowScanners           bool
+	DiffUpdates           bool
 
 	OutName   string
 	BuildTags string
@@ -39,6 +61,19 @@ type Generator struct {
 
 // writeStub outputs an initial stub for marshalers/unmarshalers so that the package
 // using marshalers/unmarshales compiles correctly for boostrapping code.
+// usesEasyCQL returns whether the stubs refer to the easycql package.
+func (g *Generator) usesEasyCQL() bool {
+	if len(g.PresenceTypes) > 0 {
+		return true
+	}
+	for _, counters := range g.TableTypes {
+		if !counters {
+			return true
+		}
+	}
+	return false
+}
+
 func (g *Generator) writeStub() error {
 	f, err := os.Create(g.OutName)
 	if err != nil {
@@ -59,6 +94,21 @@ func (g *Generator) writeStub() error {
 		fmt.Fprintln(f)
 		fmt.Fprintln(f, "import (")
 		fmt.Fprintln(f, `  "`+pkgGocql+`"`)
+		if g.usesEasyCQL() {
+			fmt.Fprintln(f, `  "`+pkgEasyCQL+`"`)
+		}
+		names := make([]string, 0, len(g.Imports))
+		for name := range g.Imports {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			path := g.Imports[name]
+			if name == "gocql" && path == pkgGocql || name == "easycql" && path == pkgEasyCQL && g.usesEasyCQL() {
+				continue
+			}
+			fmt.Fprintf(f, "  %s %q\n", name, path)
+		}
 		fmt.Fprintln(f, ")")
 	}
 
@@ -68,14 +118,74 @@ func (g *Generator) writeStub() error {
 
 		fmt.Fprintln(f, "func (", t, ") MarshalCQL(info gocql.TypeInfo) ([]byte, error) {return nil, nil}")
 		fmt.Fprintln(f, "func (*", t, ") UnmarshalCQL(info gocql.TypeInfo, data []byte) error {return nil}")
+		if g.PresenceTypes[t] {
+			fmt.Fprintln(f, "func (*", t, ") CQLFieldState(name string) easycql.FieldState {return 0}")
+		}
+		if counters, ok := g.TableTypes[t]; ok {
+			if !counters {
+				fmt.Fprintln(f, "func (", t, ") InsertCQL(using easycql.Using) string {return \"\"}")
+				fmt.Fprintln(f, "func (", t, ") UpdateCQL(using easycql.Using) string {return \"\"}")
+				fmt.Fprintln(f, "func (", t, ") BindValues() []interface{} {return nil}")
+			}
+			fmt.Fprintln(f, "func (", t, ") SelectByKeyCQL() string {return \"\"}")
+			fmt.Fprintln(f, "func (", t, ") DeleteCQL() string {return \"\"}")
+			fmt.Fprintln(f, "func (", t, ") KeyBindValues() []interface{} {return nil}")
+			for _, c := range g.CollectionFields[t] {
+				writeCollectionStubs(f, t, c)
+			}
+			if counters {
+				fmt.Fprintln(f, "type", t+"Delta", "struct {")
+				for _, field := range g.CounterFields[t] {
+					fmt.Fprintln(f, field, "int64")
+				}
+				fmt.Fprintln(f, "}")
+				fmt.Fprintln(f, "func (", t, ") IncrementCQL(delta", t+"Delta) (string, []interface{}) {return \"\", nil}")
+			}
+		}
+		if g.RowScanners {
+			fmt.Fprintln(f, "func (*", t, ") ScanCQL(columns []gocql.ColumnInfo, values [][]byte) error {return nil}")
+		}
+		if g.DiffUpdates {
+			fmt.Fprintln(f, "func (*", t, ") DiffCQL(column string, old *", t, ") ([]string, []interface{}) {return nil, nil}")
+		}
+		if g.UDTMarshalers {
+			fmt.Fprintln(f, "func (", t, ") MarshalUDT(name string, info gocql.TypeInfo) ([]byte, error) {return nil, nil}")
+			fmt.Fprintln(f, "func (*", t, ") UnmarshalUDT(name string, info gocql.TypeInfo, data []byte) error {return nil}")
+		}
 		fmt.Fprintln(f)
 		fmt.Fprintln(f, "type EasyCQL_exporter_"+t+" *"+t)
 	}
 	return nil
 }
 
+// writeCollectionStubs outputs stubs of methods modifying elements of collection field c of type t.
+func writeCollectionStubs(f io.Writer, t string, c parser.CollectionField) {
+	const ret = "(string, []interface{}) {return \"\", nil}"
+	if c.Key != "" {
+		fmt.Fprintln(f, "func (*", t, ") Put"+c.Name+"CQL(key", c.Key+", value", c.Elem+")", ret)
+		fmt.Fprintln(f, "func (*", t, ") Delete"+c.Name+"CQL(key", c.Key+")", ret)
+		return
+	}
+	if c.List {
+		fmt.Fprintln(f, "func (*", t, ") Append"+c.Name+"CQL(elems ..."+c.Elem+")", ret)
+		fmt.Fprintln(f, "func (*", t, ") Prepend"+c.Name+"CQL(elems ..."+c.Elem+")", ret)
+	}
+	if c.Set {
+		fmt.Fprintln(f, "func (*", t, ") Add"+c.Name+"CQL(elems ..."+c.Elem+")", ret)
+	}
+	fmt.Fprintln(f, "func (*", t, ") Remove"+c.Name+"CQL(elems ..."+c.Elem+")", ret)
+}
+
 // writeMain creates a .go file that launches the generator if 'go run'.
 func (g *Generator) writeMain() (path string, err error) {
+	var namerPkg, namerType string
+	if g.FieldNamer != "" {
+		namerPkg, namerType, err = splitTypePath(g.FieldNamer)
+		if err != nil {
+			return "", fmt.Errorf("invalid field namer: %v", err)
+		}
+	}
+
 	f, err := ioutil.TempFile(filepath.Dir(g.OutName), "easycql-bootstrap")
 	if err != nil {
 		return "", err
@@ -98,6 +208,9 @@ import (
 		fmt.Fprintln(f)
 		fmt.Fprintf(f, "  pkg %q\n", g.PkgPath)
 	}
+	if namerPkg != "" {
+		fmt.Fprintf(f, "  namer %q\n", namerPkg)
+	}
 	fmt.Fprintln(f, ")")
 	fmt.Fprintln(f)
 	fmt.Fprintln(f, "func main() {")
@@ -112,12 +225,30 @@ import (
 	if g.LowerCamelCase {
 		fmt.Fprintln(f, "  g.UseLowerCamelCase()")
 	}
+	if namerType != "" {
+		fmt.Fprintf(f, "  g.SetFieldNamer(namer.%s{})\n", namerType)
+	}
 	if g.Conservative {
 		fmt.Fprintln(f, "  g.Conservative()")
 	}
 	if g.DisallowUnknownFields {
 		fmt.Fprintln(f, "  g.DisallowUnknownFields()")
 	}
+	if g.ResetOnDecode {
+		fmt.Fprintln(f, "  g.ResetOnDecode()")
+	}
+	if g.PointerMarshalers {
+		fmt.Fprintln(f, "  g.PointerMarshalers()")
+	}
+	if g.UDTMarshalers {
+		fmt.Fprintln(f, "  g.UDTMarshalers()")
+	}
+	if g.RowScanners {
+		fmt.Fprintln(f, "  g.RowScanners()")
+	}
+	if g.DiffUpdates {
+		fmt.Fprintln(f, "  g.DiffUpdates()")
+	}
 
 	sort.Strings(g.Types)
 	for _, v := range g.Types {
@@ -139,6 +270,15 @@ import (
 	return dest, os.Rename(src, dest)
 }
 
+// splitTypePath splits a type path in form importpath.TypeName into the import path and the type name.
+func splitTypePath(typePath string) (pkgPath, typeName string, err error) {
+	i := strings.LastIndex(typePath, ".")
+	if i <= 0 || i == len(typePath)-1 || strings.LastIndex(typePath, "/") > i {
+		return "", "", fmt.Errorf("%q is not in form importpath.TypeName", typePath)
+	}
+	return typePath[:i], typePath[i+1:], nil
+}
+
 func (g *Generator) Run() error {
 	if err := g.writeStub(); err != nil {
 		return err
@@ -22,6 +22,7 @@ var (
 	buildTags             = flag.String("build_tags", "", "build tags to add to generated file")
 	snakeCase             = flag.Bool("snake_case", false, "use snake_case names instead of CamelCase by default")
 	lowerCamelCase        = flag.Bool("lower_camel_case", false, "use lowerCamelCase names instead of CamelCase by default")
+	fieldNamer            = flag.String("field_namer", "", "use custom gen.FieldNamer given as importpath.TypeName")
 	allStructs            = flag.Bool("all", false, "generate marshaler/unmarshalers for all structs in a file")
 	leaveTemps            = flag.Bool("leave_temps", false, "do not delete temporary files")
 	stubs                 = flag.Bool("stubs", false, "only generate stubs for marshaler/unmarshaler funcs")
@@ -29,7 +30,12 @@ var (
 	specifiedName         = flag.String("output_filename", "", "specify the filename of the output")
 	processPkg            = flag.Bool("pkg", false, "process the whole package instead of just the given file")
 	disallowUnknownFields = flag.Bool("disallow_unknown_fields", false, "return error if any unknown field in json appeared")
+	resetOnDecode         = flag.Bool("reset_on_decode", false, "zero structs before decoding instead of merging into them")
+	pointerMarshalers     = flag.Bool("pointer_marshalers", false, "generate MarshalCQL of structs with pointer receivers")
 	conservative          = flag.Bool("conservative", false, "be conservative about generated code, mostly falls back to gocql")
+	udtMarshalers         = flag.Bool("udt_marshalers", false, "generate also MarshalUDT/UnmarshalUDT methods")
+	diffUpdates           = flag.Bool("diff_updates", false, "generate also DiffCQL methods updating changed fields of UDT columns")
+	rowScanners           = flag.Bool("row_scanners", false, "generate also ScanCQL methods decoding rows of query results")
 )
 
 func generate(fname string) (err error) {
@@ -68,10 +74,21 @@ func generate(fname string) (err error) {
 		PkgPath:               p.PkgPath,
 		PkgName:               p.PkgName,
 		Types:                 p.StructNames,
+		TableTypes:            p.TableStructs,
+		PresenceTypes:         p.PresenceStructs,
+		CounterFields:         p.CounterFields,
+		CollectionFields:      p.CollectionFields,
+		Imports:               p.Imports,
 		SnakeCase:             *snakeCase,
 		LowerCamelCase:        *lowerCamelCase,
+		FieldNamer:            *fieldNamer,
 		DisallowUnknownFields: *disallowUnknownFields,
 		Conservative:          *conservative,
+		ResetOnDecode:         *resetOnDecode,
+		PointerMarshalers:     *pointerMarshalers,
+		UDTMarshalers:         *udtMarshalers,
+		RowScanners:           *rowScanners,
+		DiffUpdates:           *diffUpdates,
 		LeaveTemps:            *leaveTemps,
 		OutName:               outName,
 		StubsOnly:             *stubs,
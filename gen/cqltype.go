@@ -0,0 +1,269 @@
+package gen
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/gocql/gocql"
+)
+
+// cqlTypeExpr is a parsed CQL type expression such as frozen<map<text,list<int>>>.
+type cqlTypeExpr struct {
+	typ    gocql.Type
+	frozen bool
+
+	// name of the user defined type, set only for gocql.TypeUDT.
+	name string
+
+	// element types of collections and tuples. list and set have a single element,
+	// map has the key and the value.
+	elems []*cqlTypeExpr
+}
+
+func (e *cqlTypeExpr) String() string {
+	var s string
+	switch e.typ {
+	case gocql.TypeUDT:
+		s = e.name
+	case gocql.TypeList, gocql.TypeSet, gocql.TypeMap, gocql.TypeTuple:
+		elems := make([]string, len(e.elems))
+		for i, elem := range e.elems {
+			elems[i] = elem.String()
+		}
+		s = e.typ.String() + "<" + strings.Join(elems, ",") + ">"
+	default:
+		s = e.typ.String()
+	}
+	if e.frozen {
+		s = "frozen<" + s + ">"
+	}
+	return s
+}
+
+// parseCQLType parses CQL type expression s into a type tree.
+// Names that are not CQL native types nor collections are considered to be names of user defined types.
+func parseCQLType(s string) (*cqlTypeExpr, error) {
+	p := cqlTypeParser{s: s}
+	expr, err := p.parseType()
+	if err != nil {
+		return nil, fmt.Errorf("invalid CQL type %q: %v", s, err)
+	}
+	p.skipSpaces()
+	if p.pos != len(p.s) {
+		return nil, fmt.Errorf("invalid CQL type %q: unexpected %q at position %d", s, p.s[p.pos:], p.pos)
+	}
+	return expr, nil
+}
+
+// cqlTypeParser is a recursive descent parser of CQL type expressions.
+type cqlTypeParser struct {
+	s   string
+	pos int
+}
+
+func (p *cqlTypeParser) skipSpaces() {
+	for p.pos < len(p.s) && p.s[p.pos] == ' ' {
+		p.pos++
+	}
+}
+
+func (p *cqlTypeParser) parseName() string {
+	p.skipSpaces()
+	start := p.pos
+	for p.pos < len(p.s) {
+		c := p.s[p.pos]
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '.') {
+			break
+		}
+		p.pos++
+	}
+	return strings.ToLower(p.s[start:p.pos])
+}
+
+func (p *cqlTypeParser) expect(c byte) error {
+	p.skipSpaces()
+	if p.pos >= len(p.s) {
+		return fmt.Errorf("expected %q at the end", c)
+	}
+	if p.s[p.pos] != c {
+		return fmt.Errorf("expected %q at position %d, got %q", c, p.pos, p.s[p.pos])
+	}
+	p.pos++
+	return nil
+}
+
+// parseParams parses a list of type parameters enclosed in <>.
+func (p *cqlTypeParser) parseParams() ([]*cqlTypeExpr, error) {
+	if err := p.expect('<'); err != nil {
+		return nil, err
+	}
+	var params []*cqlTypeExpr
+	for {
+		param, err := p.parseType()
+		if err != nil {
+			return nil, err
+		}
+		params = append(params, param)
+
+		p.skipSpaces()
+		if p.pos < len(p.s) && p.s[p.pos] == ',' {
+			p.pos++
+			continue
+		}
+		return params, p.expect('>')
+	}
+}
+
+func (p *cqlTypeParser) parseType() (*cqlTypeExpr, error) {
+	name := p.parseName()
+	if name == "" {
+		return nil, fmt.Errorf("expected type name at position %d", p.pos)
+	}
+
+	if name == "frozen" {
+		params, err := p.parseParams()
+		if err != nil {
+			return nil, err
+		}
+		if len(params) != 1 {
+			return nil, fmt.Errorf("frozen expects 1 type parameter, got %d", len(params))
+		}
+		params[0].frozen = true
+		return params[0], nil
+	}
+
+	typ, ok := gocqlTypeNameToID[name]
+	if !ok {
+		return &cqlTypeExpr{typ: gocql.TypeUDT, name: name}, nil
+	}
+
+	expr := &cqlTypeExpr{typ: typ}
+	var expectedParams int
+	switch typ {
+	case gocql.TypeList, gocql.TypeSet:
+		expectedParams = 1
+	case gocql.TypeMap:
+		expectedParams = 2
+	case gocql.TypeTuple:
+		expectedParams = -1
+	default:
+		return expr, nil
+	}
+
+	params, err := p.parseParams()
+	if err != nil {
+		return nil, err
+	}
+	if expectedParams > 0 && len(params) != expectedParams {
+		return nil, fmt.Errorf("%s expects %d type parameters, got %d", name, expectedParams, len(params))
+	}
+	expr.elems = params
+	return expr, nil
+}
+
+// isCollection returns whether the expression is a list, set or map.
+func (e *cqlTypeExpr) isCollection() bool {
+	switch e.typ {
+	case gocql.TypeList, gocql.TypeSet, gocql.TypeMap:
+		return true
+	}
+	return false
+}
+
+// elemTags returns field tags used to decode i-th element of the collection or tuple.
+func (e *cqlTypeExpr) elemTags(i int) fieldTags {
+	elem := e.elems[i]
+	return fieldTags{
+		cqlTypeSet:  true,
+		cqlType:     elem.typ,
+		cqlTypeExpr: elem,
+	}
+}
+
+// checkCQLType validates that Go type t can be (un)marshaled to/from CQL type expr.
+func checkCQLType(t reflect.Type, expr *cqlTypeExpr) error {
+	// Custom (un)marshalers can handle any type.
+	for _, iface := range []reflect.Type{
+		reflect.TypeOf((*gocql.Marshaler)(nil)).Elem(),
+		reflect.TypeOf((*gocql.Unmarshaler)(nil)).Elem(),
+		reflect.TypeOf((*gocql.UDTMarshaler)(nil)).Elem(),
+		reflect.TypeOf((*gocql.UDTUnmarshaler)(nil)).Elem(),
+	} {
+		if implements(t, iface) {
+			return nil
+		}
+	}
+
+	if t.Kind() == reflect.Ptr {
+		return checkCQLType(t.Elem(), expr)
+	}
+	if t.Kind() == reflect.Interface {
+		return nil
+	}
+
+	switch expr.typ {
+	case gocql.TypeList, gocql.TypeSet:
+		if t.Kind() != reflect.Slice && t.Kind() != reflect.Array {
+			return fmt.Errorf("cannot use %v for %s", t, expr)
+		}
+		return checkCQLType(t.Elem(), expr.elems[0])
+	case gocql.TypeMap:
+		if t.Kind() != reflect.Map {
+			return fmt.Errorf("cannot use %v for %s", t, expr)
+		}
+		if err := checkCQLType(t.Key(), expr.elems[0]); err != nil {
+			return err
+		}
+		return checkCQLType(t.Elem(), expr.elems[1])
+	case gocql.TypeUDT, gocql.TypeTuple:
+		if t.Kind() != reflect.Struct && t.Kind() != reflect.Map && t.Kind() != reflect.Slice {
+			return fmt.Errorf("cannot use %v for %s", t, expr)
+		}
+		return nil
+	}
+
+	dm, ok := decodersByType[t]
+	if !ok {
+		dm, ok = decodersByKind[t.Kind()]
+	}
+	if ok {
+		if _, supported := dm.cqlTypes[expr.typ]; !supported && dm.complete {
+			return fmt.Errorf("cannot use %v for %s", t, expr)
+		}
+		return nil
+	}
+
+	switch t.Kind() {
+	case reflect.Slice, reflect.Array, reflect.Map:
+		if t.Elem().Kind() != reflect.Uint8 {
+			return fmt.Errorf("cannot use %v for %s", t, expr)
+		}
+	}
+	return nil
+}
+
+// SplitTagOptions splits the easycql tag into comma separated options. Commas inside <> of CQL type
+// expressions don't separate options. It is exported for the parser, which reads the options of tags without
+// loading the types.
+func SplitTagOptions(tag string) []string {
+	var options []string
+	depth := 0
+	start := 0
+	for i := 0; i < len(tag); i++ {
+		switch tag[i] {
+		case '<':
+			depth++
+		case '>':
+			if depth > 0 {
+				depth--
+			}
+		case ',':
+			if depth == 0 {
+				options = append(options, tag[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(options, tag[start:])
+}
@@ -5,11 +5,14 @@ import (
 	"math/big"
 	"reflect"
 	"sort"
+	"strconv"
 	"strings"
 	"unicode"
 
 	"github.com/gocql/gocql"
 	"gopkg.in/inf.v0"
+
+	"github.com/kiwicom/easycql"
 )
 
 func (g *Generator) getDecoderName(t reflect.Type) string {
@@ -475,6 +478,32 @@ func doubleToFloat64Decoder(g *Generator, t reflect.Type, info, in, out string,
 func (g *Generator) genTypeDecoder(t reflect.Type, info, in, out string, tags fieldTags, indent int) error {
 	ws := strings.Repeat("  ", indent)
 
+	if tags.defaultSet {
+		fmt.Fprintln(g.out, ws+"if "+in+" == nil {")
+		g.genDefaultValueAssign(t, out, tags, indent+1)
+		fmt.Fprintln(g.out, ws+"} else {")
+		tags.defaultSet = false
+		if err := g.genTypeDecoder(t, info, in, out, tags, indent+1); err != nil {
+			return err
+		}
+		fmt.Fprintln(g.out, ws+"}")
+		return nil
+	}
+
+	if tags.raw {
+		g.genBytesCopy(g.getType(t), in, out, indent)
+		return nil
+	}
+
+	if tags.codec != "" {
+		codecErr := g.uniqueVarName()
+		fmt.Fprintln(g.out, ws+"if "+codecErr+" := "+g.codecFuncName(tags.codec, "Decode")+"("+info+", "+in+", "+
+			reference(out)+"); "+codecErr+" != nil {")
+		fmt.Fprintln(g.out, ws+"  return "+codecErr)
+		fmt.Fprintln(g.out, ws+"}")
+		return nil
+	}
+
 	unmarshalerIface := reflect.TypeOf((*gocql.Unmarshaler)(nil)).Elem()
 	if reflect.PtrTo(t).Implements(unmarshalerIface) {
 		fallbackErr := g.uniqueVarName()
@@ -485,10 +514,52 @@ func (g *Generator) genTypeDecoder(t reflect.Type, info, in, out string, tags fi
 		return nil
 	}
 
+	udtUnmarshalerIface := reflect.TypeOf((*gocql.UDTUnmarshaler)(nil)).Elem()
+	if reflect.PtrTo(t).Implements(udtUnmarshalerIface) {
+		g.genUDTUnmarshalerDecoder(info, in, out, indent)
+		return nil
+	}
+
 	err := g.genTypeDecoderNoCheck(t, info, in, out, tags, indent)
 	return err
 }
 
+// genUDTUnmarshalerDecoder generates code that decodes in field by field using gocql.UDTUnmarshaler interface.
+func (g *Generator) genUDTUnmarshalerDecoder(info, in, out string, indent int) {
+	ws := strings.Repeat("  ", indent)
+
+	udt := g.uniqueVarName()
+	ok := g.uniqueVarName()
+	udtData := g.uniqueVarName()
+	udtElement := g.uniqueVarName()
+	elementData := g.uniqueVarName()
+	elementErr := g.uniqueVarName()
+	fmt.Fprintln(g.out, ws+udt+", "+ok+" := "+info+".(gocql.UDTTypeInfo)")
+	fmt.Fprintln(g.out, ws+"if !"+ok+" {")
+	fmt.Fprintln(g.out, ws+"  return fmt.Errorf(\"cannot unmarshal non-udt type %s to %T\", "+info+", "+reference(out)+")")
+	fmt.Fprintln(g.out, ws+"}")
+	fmt.Fprintln(g.out, ws+"if "+info+".Version() < 3 {")
+	fmt.Fprintln(g.out, ws+"  return marshal.ErrorUDTUnavailable")
+	fmt.Fprintln(g.out, ws+"}")
+	fmt.Fprintln(g.out, ws+udtData+" := "+in)
+	fmt.Fprintln(g.out, ws+"for _, "+udtElement+" := range "+udt+".Elements {")
+	fmt.Fprintln(g.out, ws+"  if len("+udtData+") == 0 {")
+	fmt.Fprintln(g.out, ws+"    break")
+	fmt.Fprintln(g.out, ws+"  }")
+	fmt.Fprintln(g.out, ws+"  var "+elementData+" []byte")
+	fmt.Fprintln(g.out, ws+"  var "+elementErr+" error")
+	fmt.Fprintln(g.out, ws+"  "+elementData+", "+udtData+", "+elementErr+" = marshal.ReadBytes2("+udtData+")")
+	fmt.Fprintln(g.out, ws+"  if "+elementErr+" != nil {")
+	fmt.Fprintf(g.out, ws+"    return fmt.Errorf(\"%%s.%%s UDT unmarshal: %%v\", %s.Name, %s.Name, %s)\n",
+		udt, udtElement, elementErr)
+	fmt.Fprintln(g.out, ws+"  }")
+	fmt.Fprintln(g.out, ws+"  if "+elementErr+" = ("+out+").UnmarshalUDT("+udtElement+".Name, "+udtElement+".Type, "+
+		elementData+"); "+elementErr+" != nil {")
+	fmt.Fprintln(g.out, ws+"    return "+elementErr)
+	fmt.Fprintln(g.out, ws+"  }")
+	fmt.Fprintln(g.out, ws+"}")
+}
+
 // sortTypes sorts types and puts preferred to the first index.
 func sortTypes(types []gocql.Type, preferred gocql.Type) {
 	if len(types) == 0 {
@@ -559,6 +630,14 @@ func (g *Generator) genCQLTypeSwitch(t reflect.Type, info, in, out string, tags
 func (g *Generator) genTypeDecoderNoCheck(t reflect.Type, info, in, out string, tags fieldTags, indent int) error {
 	ws := strings.Repeat("  ", indent)
 
+	// Full CQL type of the collection is known, decode it in place so that its elements use their preferred types.
+	if tags.cqlTypeExpr != nil && tags.cqlTypeExpr.isCollection() && !g.conservative {
+		switch t.Kind() {
+		case reflect.Slice, reflect.Array, reflect.Map:
+			return g.genCollectionDecoder(t, info, in, out, tags, indent)
+		}
+	}
+
 	if decoderMeta, ok := decodersByType[t]; ok {
 		return g.genCQLTypeSwitch(t, info, in, out, tags, indent, decoderMeta)
 	}
@@ -596,8 +675,10 @@ func reference(out string) string {
 	return "&" + out
 }
 
+// genStructFieldDecoder generates a case decoding field f of struct t. When presenceField is set, the state of
+// the field is recorded as index-th field into it.
 //nolint:gocritic // parameter f is huge
-func (g *Generator) genStructFieldDecoder(t reflect.Type, f reflect.StructField) error {
+func (g *Generator) genStructFieldDecoder(t reflect.Type, f reflect.StructField, presenceField string, index int) error {
 	tags, err := parseFieldTags(f)
 	if err != nil {
 		return err
@@ -607,17 +688,56 @@ func (g *Generator) genStructFieldDecoder(t reflect.Type, f reflect.StructField)
 		return nil
 	}
 
-	cqlName := g.getFieldName(t, f, tags)
-
-	fmt.Fprintf(g.out, "    case %q:\n", cqlName)
+	fmt.Fprintf(g.out, "    case %s:\n", caseNames(g.getFieldNames(t, f, tags)))
+	if presenceField != "" {
+		fmt.Fprintf(g.out, "      out.%s.Record(%d, elementData)\n", presenceField, index)
+	}
+	if tags.notNull {
+		fmt.Fprintln(g.out, "      if elementData == nil {")
+		fmt.Fprintf(g.out, "        return fmt.Errorf(\"%%s.%%s UDT unmarshal: null value not allowed\", udt.Name, udtElement.Name)\n")
+		fmt.Fprintln(g.out, "      }")
+		tags.defaultSet = false
+	}
 	if err := g.genTypeDecoder(f.Type, "udtElement.Type", "elementData", "out."+f.Name, tags, 3); err != nil {
 		return err
 	}
 
 	if tags.required {
-		fmt.Fprintf(g.out, "%sSet = true\n", f.Name)
+		fmt.Fprintf(g.out, "%s = true\n", setVarName(f))
+	}
+
+	return nil
+}
+
+// genDefaultValueAssign generates code that assigns the default value from tags to out of type t.
+func (g *Generator) genDefaultValueAssign(t reflect.Type, out string, tags fieldTags, indent int) {
+	ws := strings.Repeat("  ", indent)
+
+	if t.Kind() != reflect.Ptr {
+		fmt.Fprintln(g.out, ws+out+" = "+g.getType(t)+"("+tags.defaultValue+")")
+		return
+	}
+
+	value := g.uniqueVarName()
+	fmt.Fprintln(g.out, ws+value+" := "+g.getType(t.Elem())+"("+tags.defaultValue+")")
+	fmt.Fprintln(g.out, ws+out+" = &"+value)
+}
+
+// genDefaultFieldValue generates code assigning the default value of field f stored in out before decoding,
+// in reset mode, where it is overwritten by a value present in data. In merge mode absent fields are left untouched,
+// so only null values get the default when they are decoded.
+//nolint:gocritic // parameter f is huge
+func (g *Generator) genDefaultFieldValue(f reflect.StructField, out string, reset bool) error {
+	tags, err := parseFieldTags(f)
+	if err != nil {
+		return err
+	}
+
+	if !reset || tags.omit || !tags.defaultSet {
+		return nil
 	}
 
+	g.genDefaultValueAssign(f.Type, out, tags, 1)
 	return nil
 }
 
@@ -632,12 +752,14 @@ func (g *Generator) genRequiredFieldSet(_ reflect.Type, f reflect.StructField) e
 		return nil
 	}
 
-	fmt.Fprintf(g.out, "var %sSet bool\n", f.Name)
+	fmt.Fprintf(g.out, "var %s bool\n", setVarName(f))
 	return nil
 }
 
+// genRequiredFieldCheck generates code returning an error when required field f was not found in the UDT.
+// ret is prepended to the error in the return statement.
 //nolint:gocritic // parameter f is huge
-func (g *Generator) genRequiredFieldCheck(t reflect.Type, f reflect.StructField) error {
+func (g *Generator) genRequiredFieldCheck(t reflect.Type, f reflect.StructField, ret string) error {
 	tags, err := parseFieldTags(f)
 	if err != nil {
 		return err
@@ -651,8 +773,8 @@ func (g *Generator) genRequiredFieldCheck(t reflect.Type, f reflect.StructField)
 
 	g.imports["fmt"] = "fmt"
 
-	fmt.Fprintf(g.out, "if !%sSet {\n", f.Name)
-	fmt.Fprintf(g.out, "    return fmt.Errorf(\"key '%s' is required\")\n", cqlName)
+	fmt.Fprintf(g.out, "if !%s {\n", setVarName(f))
+	fmt.Fprintf(g.out, "    return %sfmt.Errorf(\"key '%s' is required\")\n", ret, cqlName)
 	fmt.Fprintf(g.out, "}\n")
 
 	return nil
@@ -673,7 +795,9 @@ func mergeStructFields(fields1, fields2 []reflect.StructField) (fields []reflect
 	return
 }
 
-func getStructFields(t reflect.Type) ([]reflect.StructField, error) {
+// getStructFields returns the fields of struct t that map to UDT fields. Fields of anonymous embedded structs and
+// fields tagged with inline are flattened into the returned list.
+func (g *Generator) getStructFields(t reflect.Type) ([]reflect.StructField, error) {
 	if t.Kind() != reflect.Struct {
 		return nil, fmt.Errorf("got %v; expected a struct", t)
 	}
@@ -694,7 +818,7 @@ func getStructFields(t reflect.Type) ([]reflect.StructField, error) {
 			t1 = t1.Elem()
 		}
 
-		fs, err := getStructFields(t1)
+		fs, err := g.getStructFields(t1)
 		if err != nil {
 			return nil, fmt.Errorf("error processing embedded field: %v", err)
 		}
@@ -708,18 +832,264 @@ func getStructFields(t reflect.Type) ([]reflect.StructField, error) {
 		if err != nil {
 			return nil, err
 		}
-		if f.Anonymous && tags.name == "" {
+		if f.Anonymous && tags.name == "" || tags.unknown || tags.presence || tags.writetime || tags.ttl {
 			continue
 		}
 
+		// Unexported fields are only included when explicitly named in the easycql tag and declared in the
+		// generated package, otherwise the generated code can't access them.
 		c := []rune(f.Name)[0]
-		if unicode.IsUpper(c) {
+		if !unicode.IsUpper(c) && (SplitTagOptions(f.Tag.Get("easycql"))[0] == "" || t.PkgPath() != g.pkgPath) {
+			continue
+		}
+
+		if tags.omit || !tags.inline {
 			fields = append(fields, f)
+			continue
 		}
+
+		fs, err := g.getInlineFields(f, tags)
+		if err != nil {
+			return nil, fmt.Errorf("error processing inline field %s: %v", f.Name, err)
+		}
+		fields = append(fields, fs...)
 	}
 	return mergeStructFields(efields, fields), nil
 }
 
+// unknownFieldsPolicy defines how the generated code handles UDT fields that don't map to any struct field.
+type unknownFieldsPolicy int
+
+const (
+	unknownFieldsIgnore unknownFieldsPolicy = iota
+	unknownFieldsError
+	unknownFieldsCapture
+)
+
+// getUnknownFieldsPolicy returns the policy for unknown UDT fields of struct t and the name of the field capturing
+// them. The policy can be set per struct by a field tagged with unknown or a blank field tagged with
+// unknown=ignore or unknown=error. Otherwise unknown fields are ignored unless DisallowUnknownFields is set.
+func (g *Generator) getUnknownFieldsPolicy(t reflect.Type) (policy unknownFieldsPolicy, captureField string, err error) {
+	policy = unknownFieldsIgnore
+	if g.disallowUnknownFields {
+		policy = unknownFieldsError
+	}
+
+	var policySet bool
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tags, err := parseFieldTags(f)
+		if err != nil {
+			return policy, "", err
+		}
+		if !tags.unknown && tags.unknownPolicy == "" {
+			continue
+		}
+		if policySet {
+			return policy, "", fmt.Errorf("multiple unknown fields policies")
+		}
+		policySet = true
+
+		switch {
+		case tags.unknown:
+			if f.Type.Kind() != reflect.Map || f.Type.Key() != stringType || f.Type.Elem() != byteSliceType {
+				return policy, "", fmt.Errorf("field %s capturing unknown fields must be map[string][]byte", f.Name)
+			}
+			policy = unknownFieldsCapture
+			captureField = f.Name
+		case tags.unknownPolicy == "error":
+			policy = unknownFieldsError
+		default:
+			policy = unknownFieldsIgnore
+		}
+	}
+	return policy, captureField, nil
+}
+
+// isResetOnDecode returns whether the struct t is zeroed before decoding. The mode can be set per struct by a blank
+// field tagged with decode=reset or decode=merge, otherwise ResetOnDecode of the generator applies.
+func (g *Generator) isResetOnDecode(t reflect.Type) (bool, error) {
+	reset := g.resetOnDecode
+
+	var modeSet bool
+	for i := 0; i < t.NumField(); i++ {
+		tags, err := parseFieldTags(t.Field(i))
+		if err != nil {
+			return false, err
+		}
+		if tags.decodeMode == "" {
+			continue
+		}
+		if modeSet {
+			return false, fmt.Errorf("multiple decode modes")
+		}
+		modeSet = true
+		reset = tags.decodeMode == "reset"
+	}
+	return reset, nil
+}
+
+// afterUnmarshalerIface is implemented by types that normalize or validate themselves after decoding.
+// Generated UnmarshalCQL calls AfterUnmarshalCQL once the value is decoded.
+var afterUnmarshalerIface = reflect.TypeOf((*interface{ AfterUnmarshalCQL() error })(nil)).Elem()
+
+var fieldPresenceType = reflect.TypeOf((*easycql.FieldPresence)(nil)).Elem()
+
+// getPresenceField returns the name of the field of struct t tagged with presence or an empty string.
+func getPresenceField(t reflect.Type) (string, error) {
+	var presenceField string
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tags, err := parseFieldTags(f)
+		if err != nil {
+			return "", err
+		}
+		if !tags.presence {
+			continue
+		}
+		if f.Type != fieldPresenceType {
+			return "", fmt.Errorf("field %s tagged with presence must be easycql.FieldPresence", f.Name)
+		}
+		if presenceField != "" {
+			return "", fmt.Errorf("multiple fields tagged with presence")
+		}
+		presenceField = f.Name
+	}
+	return presenceField, nil
+}
+
+// genStructFieldState generates CQLFieldState method returning the state of fields recorded in the field tagged
+// with presence. Nothing is generated when there is no such field.
+func (g *Generator) genStructFieldState(t reflect.Type) error {
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+
+	presenceField, err := getPresenceField(t)
+	if err != nil {
+		return fmt.Errorf("cannot generate CQLFieldState for %v: %v", t, err)
+	}
+	if presenceField == "" {
+		return nil
+	}
+
+	fs, err := g.getStructFields(t)
+	if err != nil {
+		return fmt.Errorf("cannot generate CQLFieldState for %v: %v", t, err)
+	}
+
+	fmt.Fprintln(g.out, "// CQLFieldState returns whether the field with the given CQL name was absent, null or set")
+	fmt.Fprintln(g.out, "// in the last decoded value.")
+	fmt.Fprintln(g.out, "func (v *"+g.getType(t)+") CQLFieldState(name string) easycql.FieldState {")
+	fmt.Fprintln(g.out, "  switch name {")
+	for i, f := range fs {
+		tags, err := parseFieldTags(f)
+		if err != nil {
+			return err
+		}
+		if tags.omit {
+			continue
+		}
+		fmt.Fprintf(g.out, "  case %s:\n", caseNames(g.getFieldNames(t, f, tags)))
+		fmt.Fprintf(g.out, "    return v.%s.Get(%d)\n", presenceField, i)
+	}
+	fmt.Fprintln(g.out, "  }")
+	fmt.Fprintln(g.out, "  return easycql.FieldAbsent")
+	fmt.Fprintln(g.out, "}")
+	return nil
+}
+
+// genUnknownFieldDecoder generates the default case of the switch on UDT element names handling unknown field
+// name with value in according to the policy. Captured values are stored into out.
+func (g *Generator) genUnknownFieldDecoder(policy unknownFieldsPolicy, out, name, in string, indent int) {
+	ws := strings.Repeat("  ", indent)
+
+	switch policy {
+	case unknownFieldsError:
+		fmt.Fprintln(g.out, ws+"default:")
+		fmt.Fprintln(g.out, ws+"  return fmt.Errorf(\"unknown field: %s\", "+name+")")
+	case unknownFieldsCapture:
+		fmt.Fprintln(g.out, ws+"default:")
+		fmt.Fprintln(g.out, ws+"  if "+out+" == nil {")
+		fmt.Fprintln(g.out, ws+"    "+out+" = make(map[string][]byte)")
+		fmt.Fprintln(g.out, ws+"  }")
+		g.genBytesCopy("[]byte", in, out+"["+name+"]", indent+1)
+	}
+}
+
+// genBytesCopy generates code storing serialized value in into out of byte slice type typ. The value is copied
+// as the data can be reused by the caller, null values stay nil to be distinguished from empty ones.
+func (g *Generator) genBytesCopy(typ, in, out string, indent int) {
+	ws := strings.Repeat("  ", indent)
+
+	fmt.Fprintln(g.out, ws+"if "+in+" == nil {")
+	fmt.Fprintln(g.out, ws+"  "+out+" = nil")
+	fmt.Fprintln(g.out, ws+"} else {")
+	fmt.Fprintln(g.out, ws+"  "+out+" = append("+typ+"{}, "+in+"...)")
+	fmt.Fprintln(g.out, ws+"}")
+}
+
+// getInlineFields returns fields of the struct field f tagged with inline. The returned fields are accessed through
+// f (their Name is a selector path such as Price.Amount) and their CQL names are prefixed by the inline prefix.
+//nolint:gocritic // parameter f is huge
+func (g *Generator) getInlineFields(f reflect.StructField, tags fieldTags) ([]reflect.StructField, error) {
+	if f.Type.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("got %v; expected a struct", f.Type)
+	}
+
+	fs, err := g.getStructFields(f.Type)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := make([]reflect.StructField, 0, len(fs))
+	for _, f1 := range fs {
+		tags1, err := parseFieldTags(f1)
+		if err != nil {
+			return nil, err
+		}
+
+		if !tags1.omit {
+			names := g.getFieldNames(f.Type, f1, tags1)
+			for i := range names {
+				names[i] = tags.inlinePrefix + names[i]
+			}
+			f1.Tag = replaceTagName(f1.Tag, strings.Join(names, "|"))
+		}
+
+		f1.Name = f.Name + "." + f1.Name
+		f1.Index = append(append([]int(nil), f.Index...), f1.Index...)
+		f1.Anonymous = false
+		ret = append(ret, f1)
+	}
+	return ret, nil
+}
+
+// replaceTagName returns struct tag with the name in easycql tag replaced by name, keeping all tag options.
+func replaceTagName(tag reflect.StructTag, name string) reflect.StructTag {
+	value := tag.Get("easycql")
+	if i := strings.Index(value, ","); i >= 0 {
+		name += value[i:]
+	}
+	return reflect.StructTag("easycql:" + strconv.Quote(name))
+}
+
+// hasDecodedFields returns whether any of fields fs is decoded from UDT elements.
+func hasDecodedFields(fs []reflect.StructField) bool {
+	for _, f := range fs {
+		if tags, err := parseFieldTags(f); err == nil && !tags.omit {
+			return true
+		}
+	}
+	return false
+}
+
+// setVarName returns name of the generated variable tracking whether the required field f was set.
+//nolint:gocritic // parameter f is huge
+func setVarName(f reflect.StructField) string {
+	return strings.ReplaceAll(f.Name, ".", "_") + "Set"
+}
+
 func (g *Generator) genDecoder(t reflect.Type) error {
 	switch t.Kind() {
 	case reflect.Slice, reflect.Array, reflect.Map:
@@ -740,8 +1110,7 @@ func (g *Generator) genSliceArrayDecoder(t reflect.Type) error {
 	typ := g.getType(t)
 
 	fmt.Fprintln(g.out, "func "+fname+"(info gocql.TypeInfo, data []byte, out *"+typ+") error {")
-	err := g.genTypeDecoderNoCheck(t, "info", "data", "*out", fieldTags{}, 1)
-	if err != nil {
+	if err := g.genCollectionDecoder(t, "info", "data", "*out", fieldTags{}, 1); err != nil {
 		return err
 	}
 	fmt.Fprintln(g.out, "  return nil")
@@ -750,6 +1119,169 @@ func (g *Generator) genSliceArrayDecoder(t reflect.Type) error {
 	return nil
 }
 
+// genCollectionDecoder generates code decoding list, set or map stored in in into out of slice/array/map type t.
+// Other CQL types are decoded by gocql.
+func (g *Generator) genCollectionDecoder(t reflect.Type, info, in, out string, tags fieldTags, indent int) error {
+	ws := strings.Repeat("  ", indent)
+
+	fmt.Fprintln(g.out, ws+"switch "+info+".Type() {")
+	var err error
+	if t.Kind() == reflect.Map {
+		fmt.Fprintln(g.out, ws+"case gocql.TypeMap:")
+		err = g.genMapDecoder(t, info, in, out, tags, indent+1)
+	} else {
+		fmt.Fprintln(g.out, ws+"case gocql.TypeList, gocql.TypeSet:")
+		err = g.genListDecoder(t, info, in, out, tags, indent+1)
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(g.out, ws+"default:")
+	// Unmarshal into the underlying type so that gocql does not call UnmarshalCQL of t recursively.
+	fallbackErr := g.uniqueVarName()
+	fmt.Fprintln(g.out, ws+"  if "+fallbackErr+" := gocql.Unmarshal("+info+", "+in+", (*"+g.getType(underlyingType(t))+")("+
+		reference(out)+")); "+fallbackErr+" != nil {")
+	fmt.Fprintln(g.out, ws+"    return "+fallbackErr)
+	fmt.Fprintln(g.out, ws+"  }")
+	fmt.Fprintln(g.out, ws+"}")
+	return nil
+}
+
+// underlyingType returns an unnamed type with the same underlying type as collection type t.
+func underlyingType(t reflect.Type) reflect.Type {
+	switch t.Kind() {
+	case reflect.Slice:
+		return reflect.SliceOf(t.Elem())
+	case reflect.Array:
+		return reflect.ArrayOf(t.Len(), t.Elem())
+	case reflect.Map:
+		return reflect.MapOf(t.Key(), t.Elem())
+	}
+	return t
+}
+
+// collectionElemTags returns field tags used to decode i-th element of a collection decoded with tags.
+// Elements have preferred CQL types when the full CQL type of the collection is known.
+func collectionElemTags(tags fieldTags, i int) fieldTags {
+	if tags.cqlTypeExpr == nil || !tags.cqlTypeExpr.isCollection() {
+		return fieldTags{}
+	}
+	return tags.cqlTypeExpr.elemTags(i)
+}
+
+// genListDecoder generates code decoding list or set stored in in into out of slice/array type t.
+// Size of the collection and its items is decoded according to the protocol version.
+func (g *Generator) genListDecoder(t reflect.Type, info, in, out string, tags fieldTags, indent int) error {
+	ws := strings.Repeat("  ", indent)
+
+	collInfo := g.uniqueVarName()
+	ok := g.uniqueVarName()
+	size := g.uniqueVarName()
+	collData := g.uniqueVarName()
+	readErr := g.uniqueVarName()
+	i := g.uniqueVarName()
+	elementData := g.uniqueVarName()
+
+	fmt.Fprintln(g.out, ws+collInfo+", "+ok+" := "+info+".(gocql.CollectionType)")
+	fmt.Fprintln(g.out, ws+"if !"+ok+" {")
+	fmt.Fprintln(g.out, ws+"  return fmt.Errorf(\"cannot unmarshal non-collection type %s to %T\", "+info+", "+reference(out)+")")
+	fmt.Fprintln(g.out, ws+"}")
+	fmt.Fprintln(g.out, ws+"if "+in+" == nil {")
+	if t.Kind() == reflect.Array {
+		fmt.Fprintln(g.out, ws+"  return fmt.Errorf(\"cannot unmarshal null %s to array %T\", "+info+", "+reference(out)+")")
+	} else {
+		fmt.Fprintln(g.out, ws+"  "+out+" = nil")
+	}
+	fmt.Fprintln(g.out, ws+"} else {")
+	fmt.Fprintln(g.out, ws+"  "+size+", "+collData+", "+readErr+" := marshal.ReadCollectionSize("+info+".Version(), "+in+")")
+	fmt.Fprintln(g.out, ws+"  if "+readErr+" != nil {")
+	fmt.Fprintln(g.out, ws+"    return fmt.Errorf(\"%s unmarshal: %v\", "+info+", "+readErr+")")
+	fmt.Fprintln(g.out, ws+"  }")
+	if t.Kind() == reflect.Array {
+		fmt.Fprintln(g.out, ws+"  if "+size+" != len("+out+") {")
+		fmt.Fprintln(g.out, ws+"    return fmt.Errorf(\"cannot unmarshal %s of size %d to %T\", "+info+", "+size+", "+
+			reference(out)+")")
+		fmt.Fprintln(g.out, ws+"  }")
+	} else {
+		fmt.Fprintln(g.out, ws+"  if "+size+" < 0 {")
+		fmt.Fprintln(g.out, ws+"    return fmt.Errorf(\"%s unmarshal: negative size %d\", "+info+", "+size+")")
+		fmt.Fprintln(g.out, ws+"  }")
+		fmt.Fprintln(g.out, ws+"  "+out+" = make("+g.getType(t)+", "+size+")")
+	}
+	fmt.Fprintln(g.out, ws+"  for "+i+" := 0; "+i+" < "+size+"; "+i+"++ {")
+	fmt.Fprintln(g.out, ws+"    var "+elementData+" []byte")
+	fmt.Fprintln(g.out, ws+"    "+elementData+", "+collData+", "+readErr+" = marshal.ReadCollectionBytes("+info+".Version(), "+
+		collData+")")
+	fmt.Fprintln(g.out, ws+"    if "+readErr+" != nil {")
+	fmt.Fprintln(g.out, ws+"      return fmt.Errorf(\"%s unmarshal: %v\", "+info+", "+readErr+")")
+	fmt.Fprintln(g.out, ws+"    }")
+	if err := g.genTypeDecoder(t.Elem(), collInfo+".Elem", elementData, "("+out+")["+i+"]",
+		collectionElemTags(tags, 0), indent+2); err != nil {
+		return err
+	}
+	fmt.Fprintln(g.out, ws+"  }")
+	fmt.Fprintln(g.out, ws+"}")
+	return nil
+}
+
+// genMapDecoder generates code decoding map stored in in into out of map type t.
+// Size of the collection and its items is decoded according to the protocol version.
+func (g *Generator) genMapDecoder(t reflect.Type, info, in, out string, tags fieldTags, indent int) error {
+	ws := strings.Repeat("  ", indent)
+
+	collInfo := g.uniqueVarName()
+	ok := g.uniqueVarName()
+	size := g.uniqueVarName()
+	collData := g.uniqueVarName()
+	readErr := g.uniqueVarName()
+	i := g.uniqueVarName()
+	keyData := g.uniqueVarName()
+	elementData := g.uniqueVarName()
+	key := g.uniqueVarName()
+	element := g.uniqueVarName()
+
+	fmt.Fprintln(g.out, ws+collInfo+", "+ok+" := "+info+".(gocql.CollectionType)")
+	fmt.Fprintln(g.out, ws+"if !"+ok+" {")
+	fmt.Fprintln(g.out, ws+"  return fmt.Errorf(\"cannot unmarshal non-collection type %s to %T\", "+info+", "+reference(out)+")")
+	fmt.Fprintln(g.out, ws+"}")
+	fmt.Fprintln(g.out, ws+"if "+in+" == nil {")
+	fmt.Fprintln(g.out, ws+"  "+out+" = nil")
+	fmt.Fprintln(g.out, ws+"} else {")
+	fmt.Fprintln(g.out, ws+"  "+size+", "+collData+", "+readErr+" := marshal.ReadCollectionSize("+info+".Version(), "+in+")")
+	fmt.Fprintln(g.out, ws+"  if "+readErr+" != nil {")
+	fmt.Fprintln(g.out, ws+"    return fmt.Errorf(\"%s unmarshal: %v\", "+info+", "+readErr+")")
+	fmt.Fprintln(g.out, ws+"  }")
+	fmt.Fprintln(g.out, ws+"  if "+size+" < 0 {")
+	fmt.Fprintln(g.out, ws+"    return fmt.Errorf(\"%s unmarshal: negative size %d\", "+info+", "+size+")")
+	fmt.Fprintln(g.out, ws+"  }")
+	fmt.Fprintln(g.out, ws+"  "+out+" = make("+g.getType(t)+", "+size+")")
+	fmt.Fprintln(g.out, ws+"  for "+i+" := 0; "+i+" < "+size+"; "+i+"++ {")
+	fmt.Fprintln(g.out, ws+"    var "+keyData+", "+elementData+" []byte")
+	fmt.Fprintln(g.out, ws+"    "+keyData+", "+collData+", "+readErr+" = marshal.ReadCollectionBytes("+info+".Version(), "+
+		collData+")")
+	fmt.Fprintln(g.out, ws+"    if "+readErr+" != nil {")
+	fmt.Fprintln(g.out, ws+"      return fmt.Errorf(\"%s unmarshal: %v\", "+info+", "+readErr+")")
+	fmt.Fprintln(g.out, ws+"    }")
+	fmt.Fprintln(g.out, ws+"    "+elementData+", "+collData+", "+readErr+" = marshal.ReadCollectionBytes("+info+".Version(), "+
+		collData+")")
+	fmt.Fprintln(g.out, ws+"    if "+readErr+" != nil {")
+	fmt.Fprintln(g.out, ws+"      return fmt.Errorf(\"%s unmarshal: %v\", "+info+", "+readErr+")")
+	fmt.Fprintln(g.out, ws+"    }")
+	fmt.Fprintln(g.out, ws+"    var "+key+" "+g.getType(t.Key()))
+	if err := g.genTypeDecoder(t.Key(), collInfo+".Key", keyData, key, collectionElemTags(tags, 0), indent+2); err != nil {
+		return err
+	}
+	fmt.Fprintln(g.out, ws+"    var "+element+" "+g.getType(t.Elem()))
+	if err := g.genTypeDecoder(t.Elem(), collInfo+".Elem", elementData, element, collectionElemTags(tags, 1),
+		indent+2); err != nil {
+		return err
+	}
+	fmt.Fprintln(g.out, ws+"    ("+out+")["+key+"] = "+element)
+	fmt.Fprintln(g.out, ws+"  }")
+	fmt.Fprintln(g.out, ws+"}")
+	return nil
+}
+
 func (g *Generator) genStructDecoder(t reflect.Type) error {
 	if t.Kind() != reflect.Struct {
 		return fmt.Errorf("cannot generate encoder/decoder for %v, not a struct type", t)
@@ -758,7 +1290,28 @@ func (g *Generator) genStructDecoder(t reflect.Type) error {
 	fname := g.getDecoderName(t)
 	typ := g.getType(t)
 
+	fs, err := g.getStructFields(t)
+	if err != nil {
+		return fmt.Errorf("cannot generate decoder for %v: %v", t, err)
+	}
+
+	presenceField, err := getPresenceField(t)
+	if err != nil {
+		return fmt.Errorf("cannot generate decoder for %v: %v", t, err)
+	}
+
+	reset, err := g.isResetOnDecode(t)
+	if err != nil {
+		return fmt.Errorf("cannot generate decoder for %v: %v", t, err)
+	}
+
 	fmt.Fprintln(g.out, "func "+fname+"(info gocql.TypeInfo, data []byte, out *"+typ+") error {")
+	if reset {
+		fmt.Fprintln(g.out, "  *out = "+typ+"{}")
+	}
+	if presenceField != "" {
+		fmt.Fprintf(g.out, "  out.%s.Reset(%d)\n", presenceField, len(fs))
+	}
 	fmt.Fprintln(g.out, "  if data == nil {")
 	fmt.Fprintln(g.out, "    return nil")
 	fmt.Fprintln(g.out, "  }")
@@ -766,6 +1319,9 @@ func (g *Generator) genStructDecoder(t reflect.Type) error {
 	fmt.Fprintln(g.out, "  if !ok {")
 	fmt.Fprintf(g.out, "    return fmt.Errorf(\"cannot unmarshal non-udt type %%s to %%T\", info, out)")
 	fmt.Fprintln(g.out, "  }")
+	fmt.Fprintln(g.out, "  if info.Version() < 3 {")
+	fmt.Fprintln(g.out, "    return marshal.ErrorUDTUnavailable")
+	fmt.Fprintln(g.out, "  }")
 
 	// Init embedded pointer fields.
 	for i := 0; i < t.NumField(); i++ {
@@ -776,13 +1332,15 @@ func (g *Generator) genStructDecoder(t reflect.Type) error {
 		fmt.Fprintln(g.out, "  out."+f.Name+" = new("+g.getType(f.Type.Elem())+")")
 	}
 
-	fs, err := getStructFields(t)
-	if err != nil {
-		return fmt.Errorf("cannot generate decoder for %v: %v", t, err)
+	for _, f := range fs {
+		err := g.genRequiredFieldSet(t, f)
+		if err != nil {
+			return err
+		}
 	}
 
 	for _, f := range fs {
-		err := g.genRequiredFieldSet(t, f)
+		err := g.genDefaultFieldValue(f, "out."+f.Name, reset)
 		if err != nil {
 			return err
 		}
@@ -790,6 +1348,7 @@ func (g *Generator) genStructDecoder(t reflect.Type) error {
 
 	fmt.Fprintln(g.out, "  for _, udtElement := range udt.Elements {")
 	fmt.Fprintln(g.out, "    if len(data) == 0 {")
+	// Values written before fields were added to the UDT are shorter, required fields are not checked for them.
 	fmt.Fprintln(g.out, "      return nil")
 	fmt.Fprintln(g.out, "    }")
 	fmt.Fprintln(g.out, "    var elementData []byte")
@@ -799,26 +1358,88 @@ func (g *Generator) genStructDecoder(t reflect.Type) error {
 	fmt.Fprintf(g.out, "      return fmt.Errorf(\"%%s.%%s UDT unmarshal: %%v\", udt.Name, udtElement.Name, readBytesErr)\n")
 	fmt.Fprintln(g.out, "    }")
 	fmt.Fprintln(g.out, "    switch udtElement.Name {")
-	for _, f := range fs {
-		if err := g.genStructFieldDecoder(t, f); err != nil {
+	for i, f := range fs {
+		if err := g.genStructFieldDecoder(t, f, presenceField, i); err != nil {
 			return err
 		}
 	}
 
-	if g.disallowUnknownFields {
-		fmt.Fprintln(g.out, "    default:")
-		fmt.Fprintf(g.out, "      return fmt.Errorf(\"unknown field: %%s\", udtElement.Name)")
+	policy, captureField, err := g.getUnknownFieldsPolicy(t)
+	if err != nil {
+		return fmt.Errorf("cannot generate decoder for %v: %v", t, err)
 	}
+	g.genUnknownFieldDecoder(policy, "out."+captureField, "udtElement.Name", "elementData", 2)
 	fmt.Fprintln(g.out, "    }")
+	if policy != unknownFieldsCapture && !hasDecodedFields(fs) {
+		// Structs without fields mapped to UDT elements don't use the element data.
+		fmt.Fprintln(g.out, "    _ = elementData")
+	}
 	fmt.Fprintln(g.out, "  }")
 
 	for _, f := range fs {
-		err := g.genRequiredFieldCheck(t, f)
+		err := g.genRequiredFieldCheck(t, f, "")
+		if err != nil {
+			return err
+		}
+	}
+
+	fmt.Fprintln(g.out, "  return nil")
+	fmt.Fprintln(g.out, "}")
+
+	return nil
+}
+
+// genStructUDTUnmarshaler generates UnmarshalUDT method implementing gocql.UDTUnmarshaler interface.
+func (g *Generator) genStructUDTUnmarshaler(t reflect.Type) error {
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+
+	typ := g.getType(t)
+
+	fs, err := g.getStructFields(t)
+	if err != nil {
+		return fmt.Errorf("cannot generate UDT unmarshaler for %v: %v", t, err)
+	}
+
+	presenceField, err := getPresenceField(t)
+	if err != nil {
+		return fmt.Errorf("cannot generate UDT unmarshaler for %v: %v", t, err)
+	}
+
+	fmt.Fprintln(g.out, "// UnmarshalUDT supports gocql.UDTUnmarshaler interface")
+	fmt.Fprintln(g.out, "func (v *"+typ+") UnmarshalUDT(name string, info gocql.TypeInfo, data []byte) error {")
+	fmt.Fprintln(g.out, "  switch name {")
+	for i, f := range fs {
+		tags, err := parseFieldTags(f)
 		if err != nil {
 			return err
 		}
+		if tags.omit {
+			continue
+		}
+
+		fmt.Fprintf(g.out, "  case %s:\n", caseNames(g.getFieldNames(t, f, tags)))
+		if presenceField != "" {
+			fmt.Fprintf(g.out, "    v.%s.RecordUDT(%d, %d, data)\n", presenceField, i, len(fs))
+		}
+		if tags.notNull {
+			fmt.Fprintln(g.out, "    if data == nil {")
+			fmt.Fprintf(g.out, "      return fmt.Errorf(\"%%s UDT unmarshal: null value not allowed\", name)\n")
+			fmt.Fprintln(g.out, "    }")
+			tags.defaultSet = false
+		}
+		if err := g.genTypeDecoder(f.Type, "info", "data", "v."+f.Name, tags, 2); err != nil {
+			return err
+		}
 	}
 
+	policy, captureField, err := g.getUnknownFieldsPolicy(t)
+	if err != nil {
+		return fmt.Errorf("cannot generate UDT unmarshaler for %v: %v", t, err)
+	}
+	g.genUnknownFieldDecoder(policy, "v."+captureField, "name", "data", 1)
+	fmt.Fprintln(g.out, "  }")
 	fmt.Fprintln(g.out, "  return nil")
 	fmt.Fprintln(g.out, "}")
 
@@ -838,7 +1459,20 @@ func (g *Generator) genStructUnmarshaler(t reflect.Type) error {
 
 	fmt.Fprintln(g.out, "// UnmarshalCQL implements custom unmarshaler as gocql.UnmarshalCQL")
 	fmt.Fprintln(g.out, "func (v *"+typ+") UnmarshalCQL(info gocql.TypeInfo, data []byte) error {")
-	fmt.Fprintln(g.out, "  return "+fname+"(info, data, v)")
+	if !implements(t, afterUnmarshalerIface) {
+		fmt.Fprintln(g.out, "  return "+fname+"(info, data, v)")
+		fmt.Fprintln(g.out, "}")
+		return nil
+	}
+
+	g.imports["fmt"] = "fmt"
+	fmt.Fprintln(g.out, "  if err := "+fname+"(info, data, v); err != nil {")
+	fmt.Fprintln(g.out, "    return err")
+	fmt.Fprintln(g.out, "  }")
+	fmt.Fprintln(g.out, "  if err := v.AfterUnmarshalCQL(); err != nil {")
+	fmt.Fprintf(g.out, "    return fmt.Errorf(\"%s.AfterUnmarshalCQL: %%w\", err)\n", t.Name())
+	fmt.Fprintln(g.out, "  }")
+	fmt.Fprintln(g.out, "  return nil")
 	fmt.Fprintln(g.out, "}")
 
 	return nil
@@ -0,0 +1,74 @@
+package gen
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// notEqualCondition returns a condition that can be used in generated code to check whether values a and b
+// of type t differ. Pointers are compared by the values they point to.
+func (g *Generator) notEqualCondition(t reflect.Type, a, b string) string {
+	switch t.Kind() {
+	case reflect.Bool, reflect.String,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		return a + " != " + b
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			g.imports["bytes"] = "bytes"
+			return "!bytes.Equal(" + a + ", " + b + ")"
+		}
+	}
+
+	// Types like time.Time define equality that differs from ==.
+	if m, ok := t.MethodByName("Equal"); ok && m.Type.NumIn() == 2 && m.Type.In(1) == t &&
+		m.Type.NumOut() == 1 && m.Type.Out(0).Kind() == reflect.Bool {
+		return "!" + a + ".Equal(" + b + ")"
+	}
+
+	g.imports["reflect"] = "reflect"
+	return "!reflect.DeepEqual(" + a + ", " + b + ")"
+}
+
+// genStructDiff generates DiffCQL method returning assignments of UDT fields that changed. Fields of non-frozen
+// UDT columns can be updated separately, so concurrent writers of different fields don't overwrite each other.
+func (g *Generator) genStructDiff(t reflect.Type) error {
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+
+	fs, err := g.getStructFields(t)
+	if err != nil {
+		return fmt.Errorf("cannot generate DiffCQL for %v: %v", t, err)
+	}
+
+	typ := g.getType(t)
+
+	fmt.Fprintln(g.out, "// DiffCQL returns assignments of fields of UDT column that differ between old and v, and their bind")
+	fmt.Fprintln(g.out, "// values. All fields are assigned when old is nil.")
+	fmt.Fprintln(g.out, "func (v *"+typ+") DiffCQL(column string, old *"+typ+") (assignments []string, values []interface{}) {")
+	for _, f := range fs {
+		tags, err := parseFieldTags(f)
+		if err != nil {
+			return err
+		}
+		if tags.omit {
+			continue
+		}
+
+		name := quoteColumnName(g.getFieldName(t, f, tags))
+		fmt.Fprintln(g.out, "  if old == nil || "+g.notEqualCondition(f.Type, "v."+f.Name, "old."+f.Name)+" {")
+		fmt.Fprintf(g.out, "    assignments = append(assignments, column+%q)\n", "."+name+" = ?")
+		value, err := g.genBindValue(statementColumn{name: name, field: f, tags: tags}, 2)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(g.out, "    values = append(values, "+value+")")
+		fmt.Fprintln(g.out, "  }")
+	}
+	fmt.Fprintln(g.out, "  return assignments, values")
+	fmt.Fprintln(g.out, "}")
+
+	return nil
+}
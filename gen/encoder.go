@@ -3,7 +3,9 @@ package gen
 import (
 	"fmt"
 	"reflect"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gocql/gocql"
 )
@@ -18,8 +20,58 @@ type fieldTags struct {
 
 	omit       bool
 	required   bool
+	omitEmpty  bool
+	notNull    bool
 	cqlTypeSet bool
 	cqlType    gocql.Type
+
+	// requiredNonZero makes the encoder reject zero values of required field, not only nil ones.
+	requiredNonZero bool
+
+	// cqlTypeExpr is the full CQL type from type= option, cqlType is set to its top-level type.
+	cqlTypeExpr *cqlTypeExpr
+
+	// defaultValue is a Go literal assigned to the field when it is null or missing.
+	defaultValue string
+	defaultSet   bool
+
+	// inline splices fields of the struct field into the parent UDT, their names prefixed by inlinePrefix.
+	inline       bool
+	inlinePrefix string
+
+	// unknown marks map[string][]byte field capturing UDT fields unknown to the struct.
+	unknown bool
+	// unknownPolicy overrides the policy for unknown UDT fields of the struct, either ignore or error.
+	unknownPolicy string
+
+	// decodeMode overrides the decoding mode of the struct, either reset or merge.
+	decodeMode string
+
+	// receiver overrides the receiver of generated marshalers of the struct, either pointer or value.
+	receiver string
+
+	// table names the table of the struct, CQL statements are generated for structs with a table.
+	table string
+	// counters marks the table as a counter table, its regular columns are counters.
+	counters bool
+	// partitionKey and clusteringKey mark columns of the primary key.
+	partitionKey  bool
+	clusteringKey bool
+
+	// writetime and ttl mark fields holding writetime() and ttl() of the column named by the tag, selected
+	// and scanned next to the column value.
+	writetime bool
+	ttl       bool
+
+	// presence marks easycql.FieldPresence field recording the state of fields on decode.
+	presence bool
+
+	// raw marks []byte field holding the serialized value of the UDT element, passed through without decoding.
+	raw bool
+
+	// codec is the name of a package-level pair of <codec>Encode/<codec>Decode functions
+	// used to (un)marshal the field instead of the generated code.
+	codec string
 }
 
 // parseFieldTags parses the json field tag into a structure.
@@ -29,7 +81,7 @@ func parseFieldTags(f reflect.StructField) (fieldTags, error) {
 
 	ret.name = f.Tag.Get("cql")
 
-	for i, s := range strings.Split(f.Tag.Get("easycql"), ",") {
+	for i, s := range SplitTagOptions(f.Tag.Get("easycql")) {
 		switch {
 		case i == 0 && s == "-":
 			ret.omit = true
@@ -37,6 +89,101 @@ func parseFieldTags(f reflect.StructField) (fieldTags, error) {
 			ret.name = s
 		case s == "required":
 			ret.required = true
+		case strings.HasPrefix(s, "required="):
+			ret.required = true
+			switch mode := strings.TrimPrefix(s, "required="); mode {
+			case "nil":
+			case "nonzero":
+				ret.requiredNonZero = true
+			default:
+				return ret, fmt.Errorf("easycql tag required of field %s: expected nil or nonzero, got %q", f.Name, mode)
+			}
+		case s == "omitempty":
+			ret.omitEmpty = true
+		case s == "notnull":
+			ret.notNull = true
+		case strings.HasPrefix(s, "default="):
+			value, err := parseDefaultValue(f.Type, strings.TrimPrefix(s, "default="))
+			if err != nil {
+				return ret, fmt.Errorf("easycql tag default of field %s: %v", f.Name, err)
+			}
+			ret.defaultValue = value
+			ret.defaultSet = true
+		case s == "inline":
+			ret.inline = true
+		case strings.HasPrefix(s, "inline="):
+			ret.inline = true
+			ret.inlinePrefix = strings.TrimPrefix(s, "inline=")
+		case strings.HasPrefix(s, "decode="):
+			ret.decodeMode = strings.TrimPrefix(s, "decode=")
+			if ret.decodeMode != "reset" && ret.decodeMode != "merge" {
+				return ret, fmt.Errorf("easycql tag decode of field %s: expected reset or merge, got %q",
+					f.Name, ret.decodeMode)
+			}
+		case strings.HasPrefix(s, "receiver="):
+			ret.receiver = strings.TrimPrefix(s, "receiver=")
+			if ret.receiver != "pointer" && ret.receiver != "value" {
+				return ret, fmt.Errorf("easycql tag receiver of field %s: expected pointer or value, got %q",
+					f.Name, ret.receiver)
+			}
+		case s == "raw":
+			if f.Type.Kind() != reflect.Slice || f.Type.Elem().Kind() != reflect.Uint8 {
+				return ret, fmt.Errorf("easycql tag raw of field %s: got %v; expected []byte", f.Name, f.Type)
+			}
+			ret.raw = true
+		case strings.HasPrefix(s, "table="):
+			ret.table = strings.TrimPrefix(s, "table=")
+			if ret.table == "" {
+				return ret, fmt.Errorf("easycql tag table of field %s requires a table name", f.Name)
+			}
+		case s == "counters":
+			ret.counters = true
+		case s == "pk":
+			ret.partitionKey = true
+		case s == "ck":
+			ret.clusteringKey = true
+		case s == "writetime":
+			if f.Type != timeType && (f.Type.Kind() != reflect.Int64 || f.Type == durationType) {
+				return ret, fmt.Errorf("easycql tag writetime of field %s: got %v; expected int64 or time.Time", f.Name, f.Type)
+			}
+			ret.writetime = true
+		case s == "ttl":
+			switch f.Type.Kind() {
+			case reflect.Int, reflect.Int32, reflect.Int64:
+			default:
+				return ret, fmt.Errorf("easycql tag ttl of field %s: got %v; expected an integer or time.Duration",
+					f.Name, f.Type)
+			}
+			ret.ttl = true
+		case s == "presence":
+			ret.presence = true
+		case s == "unknown":
+			ret.unknown = true
+		case strings.HasPrefix(s, "unknown="):
+			ret.unknownPolicy = strings.TrimPrefix(s, "unknown=")
+			if ret.unknownPolicy != "ignore" && ret.unknownPolicy != "error" {
+				return ret, fmt.Errorf("easycql tag unknown of field %s: expected ignore or error, got %q",
+					f.Name, ret.unknownPolicy)
+			}
+		case strings.HasPrefix(s, "codec="):
+			ret.codec = strings.TrimPrefix(s, "codec=")
+			if ret.codec == "" {
+				return ret, fmt.Errorf("easycql tag codec requires a function name")
+			}
+		case strings.HasPrefix(s, "type="):
+			expr, err := parseCQLType(strings.TrimPrefix(s, "type="))
+			if err != nil {
+				return ret, fmt.Errorf("easycql tag type of field %s: %v", f.Name, err)
+			}
+			if err := checkCQLType(f.Type, expr); err != nil {
+				return ret, fmt.Errorf("easycql tag type of field %s: %v", f.Name, err)
+			}
+			if ret.cqlTypeSet {
+				return ret, fmt.Errorf("easycql tags %s and %s conflict", ret.cqlType.String(), s)
+			}
+			ret.cqlType = expr.typ
+			ret.cqlTypeSet = true
+			ret.cqlTypeExpr = expr
 		case isCQLTypeName(s):
 			if ret.cqlTypeSet {
 				return ret, fmt.Errorf("easycql tags %s and %s conflict", ret.cqlType.String(), s)
@@ -46,9 +193,62 @@ func parseFieldTags(f reflect.StructField) (fieldTags, error) {
 		}
 	}
 
+	if ret.writetime && ret.ttl {
+		return ret, fmt.Errorf("easycql tags writetime and ttl of field %s conflict", f.Name)
+	}
 	return ret, nil
 }
 
+var (
+	durationType = reflect.TypeOf((*time.Duration)(nil)).Elem()
+	timeType     = reflect.TypeOf((*time.Time)(nil)).Elem()
+)
+
+// parseDefaultValue parses default value s for a field of type t and returns a Go literal representing it.
+func parseDefaultValue(t reflect.Type, s string) (string, error) {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if t == durationType {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return "", err
+		}
+		return strconv.FormatInt(int64(d), 10), nil
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return strconv.Quote(s), nil
+	case reflect.Bool:
+		v, err := strconv.ParseBool(s)
+		if err != nil {
+			return "", err
+		}
+		return strconv.FormatBool(v), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		v, err := strconv.ParseInt(s, 10, t.Bits())
+		if err != nil {
+			return "", err
+		}
+		return strconv.FormatInt(v, 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		v, err := strconv.ParseUint(s, 10, t.Bits())
+		if err != nil {
+			return "", err
+		}
+		return strconv.FormatUint(v, 10), nil
+	case reflect.Float32, reflect.Float64:
+		v, err := strconv.ParseFloat(s, t.Bits())
+		if err != nil {
+			return "", err
+		}
+		return strconv.FormatFloat(v, 'g', -1, t.Bits()), nil
+	}
+	return "", fmt.Errorf("default values are not supported for %v", t)
+}
+
 var gocqlTypeNameToID = map[string]gocql.Type{
 	"custom":    gocql.TypeCustom,
 	"ascii":     gocql.TypeAscii,
@@ -83,19 +283,214 @@ func isCQLTypeName(s string) bool {
 	return ok
 }
 
+// codecFuncName returns the name of the codec function with the given suffix that can be used in generated code.
+// The codec may be qualified by a package path, e.g. github.com/org/pkg.LegacyTime.
+func (g *Generator) codecFuncName(codec, suffix string) string {
+	if i := strings.LastIndex(codec, "."); i != -1 {
+		return g.pkgAlias(codec[:i]) + "." + codec[i+1:] + suffix
+	}
+	return codec + suffix
+}
+
+// genCodecEncoder generates code that encodes in using the custom codec from field tags.
+func (g *Generator) genCodecEncoder(info, in string, tags fieldTags, indent int) {
+	ws := strings.Repeat("  ", indent)
+
+	codecErr := g.uniqueVarName()
+	marshaledBytes := g.uniqueVarName()
+	fmt.Fprintln(g.out, ws+marshaledBytes+", "+codecErr+" := "+g.codecFuncName(tags.codec, "Encode")+"("+info+", "+in+")")
+	fmt.Fprintln(g.out, ws+"if "+codecErr+" != nil {")
+	fmt.Fprintln(g.out, ws+"  return nil, "+codecErr)
+	fmt.Fprintln(g.out, ws+"}")
+	fmt.Fprintln(g.out, ws+"buf = marshal.AppendBytes(buf, "+marshaledBytes+")")
+}
+
+var marshalerIface = reflect.TypeOf((*gocql.Marshaler)(nil)).Elem()
+
+// beforeMarshalerIface is implemented by types that need to be prepared before marshaling, e.g. to compute derived
+// fields. Generated MarshalCQL calls BeforeMarshalCQL on the value being marshaled.
+var beforeMarshalerIface = reflect.TypeOf((*interface{ BeforeMarshalCQL() error })(nil)).Elem()
+
+// isMarshalerByPointer returns whether values of t are passed to gocql by pointer, so that MarshalCQL with
+// a pointer receiver is called instead of gocql falling back to reflection. Stubs used while generating the code
+// have value receivers, so the receiver can't be told from t.
+func isMarshalerByPointer(t reflect.Type) bool {
+	return t.Kind() != reflect.Ptr && reflect.PtrTo(t).Implements(marshalerIface)
+}
+
+// genMarshalerValue returns the expression to pass in of type t to gocql as a bind value. Marshalers are passed
+// by pointer to a copy, taking the address of a field would move the whole struct holding it to the heap.
+func (g *Generator) genMarshalerValue(t reflect.Type, in string, indent int) string {
+	if !isMarshalerByPointer(t) {
+		return in
+	}
+	value := g.uniqueVarName()
+	fmt.Fprintln(g.out, strings.Repeat("  ", indent)+value+" := "+in)
+	return "&" + value
+}
+
+// genMarshalCall returns the expression marshaling in of type t with info. Marshalers called by pointer are
+// copied to a local variable and their MarshalCQL is called directly, so that neither the copy nor the struct
+// holding in escape to the heap through gocql.Marshal.
+func (g *Generator) genMarshalCall(t reflect.Type, info, in string, indent int) string {
+	if !isMarshalerByPointer(t) {
+		return "gocql.Marshal(" + info + ", " + in + ")"
+	}
+	value := g.uniqueVarName()
+	fmt.Fprintln(g.out, strings.Repeat("  ", indent)+value+" := "+in)
+	return value + ".MarshalCQL(" + info + ")"
+}
+
+// isPointerMarshaler returns whether MarshalCQL of struct t has a pointer receiver. The receiver can be set per
+// struct by a blank field tagged with receiver=pointer or receiver=value, otherwise PointerMarshalers of
+// the generator applies.
+func (g *Generator) isPointerMarshaler(t reflect.Type) (bool, error) {
+	if t.Kind() != reflect.Struct {
+		return false, nil
+	}
+
+	ptr := g.pointerMarshalers
+
+	var receiverSet bool
+	for i := 0; i < t.NumField(); i++ {
+		tags, err := parseFieldTags(t.Field(i))
+		if err != nil {
+			return false, err
+		}
+		if tags.receiver == "" {
+			continue
+		}
+		if receiverSet {
+			return false, fmt.Errorf("multiple marshaler receivers")
+		}
+		receiverSet = true
+		ptr = tags.receiver == "pointer"
+	}
+	return ptr, nil
+}
+
+// implements returns whether t or a pointer to t implements the interface iface.
+func implements(t, iface reflect.Type) bool {
+	return t.Implements(iface) || reflect.PtrTo(t).Implements(iface)
+}
+
+// genUDTMarshalerEncoder generates code that encodes in field by field using gocql.UDTMarshaler interface.
+func (g *Generator) genUDTMarshalerEncoder(t reflect.Type, info, in string, indent int) {
+	ws := strings.Repeat("  ", indent)
+
+	if t.Kind() == reflect.Ptr {
+		fmt.Fprintln(g.out, ws+"if "+in+" == nil {")
+		fmt.Fprintln(g.out, ws+"  buf = marshal.AppendBytes(buf, nil)")
+		fmt.Fprintln(g.out, ws+"} else {")
+		defer fmt.Fprintln(g.out, ws+"}")
+		ws += "  "
+	}
+
+	udt := g.uniqueVarName()
+	ok := g.uniqueVarName()
+	udtBuf := g.uniqueVarName()
+	udtElement := g.uniqueVarName()
+	elementData := g.uniqueVarName()
+	elementErr := g.uniqueVarName()
+	fmt.Fprintln(g.out, ws+udt+", "+ok+" := "+info+".(gocql.UDTTypeInfo)")
+	fmt.Fprintln(g.out, ws+"if !"+ok+" {")
+	fmt.Fprintln(g.out, ws+"  return nil, fmt.Errorf(\"cannot marshal %T to non-udt type %s\", "+in+", "+info+")")
+	fmt.Fprintln(g.out, ws+"}")
+	fmt.Fprintln(g.out, ws+"if "+info+".Version() < 3 {")
+	fmt.Fprintln(g.out, ws+"  return nil, marshal.ErrorUDTUnavailable")
+	fmt.Fprintln(g.out, ws+"}")
+	fmt.Fprintln(g.out, ws+"var "+udtBuf+" []byte")
+	fmt.Fprintln(g.out, ws+"for _, "+udtElement+" := range "+udt+".Elements {")
+	fmt.Fprintln(g.out, ws+"  "+elementData+", "+elementErr+" := "+in+".MarshalUDT("+udtElement+".Name, "+
+		udtElement+".Type)")
+	fmt.Fprintln(g.out, ws+"  if "+elementErr+" != nil {")
+	fmt.Fprintln(g.out, ws+"    return nil, "+elementErr)
+	fmt.Fprintln(g.out, ws+"  }")
+	fmt.Fprintln(g.out, ws+"  "+udtBuf+" = marshal.AppendBytes("+udtBuf+", "+elementData+")")
+	fmt.Fprintln(g.out, ws+"}")
+	fmt.Fprintln(g.out, ws+"buf = marshal.AppendBytes(buf, "+udtBuf+")")
+}
+
+var (
+	isZeroerIface = reflect.TypeOf((*interface{ IsZero() bool })(nil)).Elem()
+	signerIface   = reflect.TypeOf((*interface{ Sign() int })(nil)).Elem()
+)
+
+// emptyCondition returns a condition that can be used in generated code to check whether in of type t is empty.
+func (g *Generator) emptyCondition(t reflect.Type, in string) (string, error) {
+	switch t.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		return in + " == nil", nil
+	case reflect.Slice, reflect.Map:
+		return "len(" + in + ") == 0", nil
+	case reflect.String:
+		return in + " == \"\"", nil
+	case reflect.Bool:
+		return "!" + in, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		return in + " == 0", nil
+	}
+
+	switch {
+	case implements(t, isZeroerIface):
+		return in + ".IsZero()", nil
+	case implements(t, signerIface):
+		return in + ".Sign() == 0", nil
+	case t.Comparable():
+		return in + " == (" + g.getType(t) + "{})", nil
+	}
+	return "", fmt.Errorf("omitempty is not supported for %v", t)
+}
+
 // genTypeEncoder generates code that encodes in of type t into the writer, but uses marshaler interface if implemented by t.
 func (g *Generator) genTypeEncoder(t reflect.Type, info, in string, tags fieldTags, indent int, assumeNonEmpty bool) error {
+	if tags.omitEmpty && !assumeNonEmpty {
+		ws := strings.Repeat("  ", indent)
+
+		cond, err := g.emptyCondition(t, in)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(g.out, ws+"if "+cond+" {")
+		fmt.Fprintln(g.out, ws+"  buf = marshal.AppendBytes(buf, nil)")
+		fmt.Fprintln(g.out, ws+"} else {")
+		if err := g.genTypeEncoder(t, info, in, tags, indent+1, true); err != nil {
+			return err
+		}
+		fmt.Fprintln(g.out, ws+"}")
+		return nil
+	}
+
+	if tags.raw {
+		fmt.Fprintln(g.out, strings.Repeat("  ", indent)+"buf = marshal.AppendBytes(buf, "+in+")")
+		return nil
+	}
+
+	if tags.codec != "" {
+		g.genCodecEncoder(info, in, tags, indent)
+		return nil
+	}
+
+	udtMarshalerIface := reflect.TypeOf((*gocql.UDTMarshaler)(nil)).Elem()
+	if !implements(t, marshalerIface) && implements(t, udtMarshalerIface) {
+		g.genUDTMarshalerEncoder(t, info, in, indent)
+		return nil
+	}
+
 	err := g.genTypeEncoderNoCheck(t, info, in, tags, indent, assumeNonEmpty)
 	return err
 }
 
 // genTypeEncoderNoCheck generates code that encodes in of type t into the writer.
-func (g *Generator) genTypeEncoderNoCheck(_ reflect.Type, info, in string, _ fieldTags, indent int, _ bool) error {
+func (g *Generator) genTypeEncoderNoCheck(t reflect.Type, info, in string, _ fieldTags, indent int, _ bool) error {
 	ws := strings.Repeat("  ", indent)
 
 	fallbackErr := g.uniqueVarName()
 	marshaledBytes := g.uniqueVarName()
-	fmt.Fprintln(g.out, ws+marshaledBytes+", "+fallbackErr+" := gocql.Marshal("+info+", "+in+")")
+	call := g.genMarshalCall(t, info, in, indent)
+	fmt.Fprintln(g.out, ws+marshaledBytes+", "+fallbackErr+" := "+call)
 	fmt.Fprintln(g.out, ws+"if "+fallbackErr+" != nil {")
 	fmt.Fprintln(g.out, ws+"  return nil, "+fallbackErr)
 	fmt.Fprintln(g.out, ws+"}")
@@ -104,6 +499,43 @@ func (g *Generator) genTypeEncoderNoCheck(_ reflect.Type, info, in string, _ fie
 	return nil
 }
 
+// requiredValueCondition returns a condition that can be used in generated code to check whether in of type t
+// is an empty value of a required field. It returns empty string when no value of t is considered empty.
+func (g *Generator) requiredValueCondition(t reflect.Type, in string, tags fieldTags) (string, error) {
+	if tags.requiredNonZero {
+		return g.emptyCondition(t, in)
+	}
+	switch t.Kind() {
+	case reflect.Ptr, reflect.Interface, reflect.Slice, reflect.Map:
+		return in + " == nil", nil
+	}
+	return "", nil
+}
+
+// genRequiredValueCheck generates code that returns an error when required field f holds an empty value.
+//nolint:gocritic // parameter f is huge
+func (g *Generator) genRequiredValueCheck(t reflect.Type, f reflect.StructField, in, ret string, tags fieldTags,
+	indent int) error {
+	if !tags.required {
+		return nil
+	}
+
+	cond, err := g.requiredValueCondition(f.Type, in, tags)
+	if err != nil {
+		return fmt.Errorf("required field %s: %v", f.Name, err)
+	}
+	if cond == "" {
+		return nil
+	}
+
+	ws := strings.Repeat("  ", indent)
+	fmt.Fprintln(g.out, ws+"if "+cond+" {")
+	fmt.Fprintf(g.out, "%s  return %sfmt.Errorf(\"key '%s' is required, got empty %s\")\n", ws, ret,
+		g.getFieldName(t, f, tags), f.Name)
+	fmt.Fprintln(g.out, ws+"}")
+	return nil
+}
+
 //nolint:gocritic // parameter f is huge
 func (g *Generator) genStructFieldEncoder(t reflect.Type, f reflect.StructField, _, firstCondition bool) (bool, error) {
 	tags, err := parseFieldTags(f)
@@ -115,14 +547,18 @@ func (g *Generator) genStructFieldEncoder(t reflect.Type, f reflect.StructField,
 		return firstCondition, nil
 	}
 
-	cqlName := g.getFieldName(t, f, tags)
-
 	toggleFirstCondition := firstCondition
 
-	fmt.Fprintf(g.out, "    case %q:\n", cqlName)
+	fmt.Fprintf(g.out, "    case %s:\n", caseNames(g.getFieldNames(t, f, tags)))
+	if err := g.genRequiredValueCheck(t, f, "in."+f.Name, "nil, ", tags, 3); err != nil {
+		return toggleFirstCondition, err
+	}
 	if err := g.genTypeEncoder(f.Type, "udtElement.Type", "in."+f.Name, tags, 2, false); err != nil {
 		return toggleFirstCondition, err
 	}
+	if tags.required {
+		fmt.Fprintf(g.out, "      %s = true\n", setVarName(f))
+	}
 	return toggleFirstCondition, nil
 }
 
@@ -146,16 +582,57 @@ func (g *Generator) genSliceArrayMapEncoder(t reflect.Type) error {
 	typ := g.getType(t)
 
 	fmt.Fprintln(g.out, "func "+fname+"(info gocql.TypeInfo, in "+typ+") ([]byte, error) {")
-	fmt.Fprintln(g.out, "var buf []byte")
-	err := g.genTypeEncoderNoCheck(t, "info", "in", fieldTags{}, 1, false)
-	if err != nil {
-		return err
+	fmt.Fprintln(g.out, "  switch info.Type() {")
+	if t.Kind() == reflect.Map {
+		fmt.Fprintln(g.out, "  case gocql.TypeMap:")
+	} else {
+		fmt.Fprintln(g.out, "  case gocql.TypeList, gocql.TypeSet:")
 	}
-	fmt.Fprintln(g.out, "return buf, nil")
+	fmt.Fprintln(g.out, "    collInfo, ok := info.(gocql.CollectionType)")
+	fmt.Fprintln(g.out, "    if !ok {")
+	fmt.Fprintf(g.out, "      return nil, fmt.Errorf(\"cannot marshal %%T to non-collection type %%s\", in, info)\n")
+	fmt.Fprintln(g.out, "    }")
+	if t.Kind() != reflect.Array {
+		fmt.Fprintln(g.out, "    if in == nil {")
+		fmt.Fprintln(g.out, "      return nil, nil")
+		fmt.Fprintln(g.out, "    }")
+	}
+	fmt.Fprintln(g.out, "    buf, err := marshal.AppendCollectionSize(info.Version(), nil, len(in))")
+	fmt.Fprintln(g.out, "    if err != nil {")
+	fmt.Fprintln(g.out, "      return nil, err")
+	fmt.Fprintln(g.out, "    }")
+	if t.Kind() == reflect.Map {
+		fmt.Fprintln(g.out, "    for key, element := range in {")
+		g.genCollectionItemEncoder(t.Key(), "collInfo.Key", "key")
+	} else {
+		fmt.Fprintln(g.out, "    for i := range in {")
+		fmt.Fprintln(g.out, "      element := in[i]")
+	}
+	g.genCollectionItemEncoder(t.Elem(), "collInfo.Elem", "element")
+	fmt.Fprintln(g.out, "    }")
+	fmt.Fprintln(g.out, "    return buf, nil")
+	fmt.Fprintln(g.out, "  default:")
+	// Marshal the underlying type so that gocql does not call MarshalCQL of t recursively.
+	fmt.Fprintln(g.out, "    return gocql.Marshal(info, "+g.getType(underlyingType(t))+"(in))")
+	fmt.Fprintln(g.out, "  }")
 	fmt.Fprintln(g.out, "}")
 	return nil
 }
 
+// genCollectionItemEncoder generates code that appends in encoded as a collection item to buf.
+// Size of the item is encoded according to the protocol version.
+func (g *Generator) genCollectionItemEncoder(t reflect.Type, info, in string) {
+	itemData := g.uniqueVarName()
+	call := g.genMarshalCall(t, info, in, 3)
+	fmt.Fprintln(g.out, "      "+itemData+", err := "+call)
+	fmt.Fprintln(g.out, "      if err != nil {")
+	fmt.Fprintln(g.out, "        return nil, err")
+	fmt.Fprintln(g.out, "      }")
+	fmt.Fprintln(g.out, "      if buf, err = marshal.AppendCollectionBytes(info.Version(), buf, "+itemData+"); err != nil {")
+	fmt.Fprintln(g.out, "        return nil, err")
+	fmt.Fprintln(g.out, "      }")
+}
+
 func (g *Generator) genStructEncoder(t reflect.Type) error {
 	if t.Kind() != reflect.Struct {
 		return fmt.Errorf("cannot generate encoder/decoder for %v, not a struct type", t)
@@ -164,18 +641,40 @@ func (g *Generator) genStructEncoder(t reflect.Type) error {
 	fname := g.getEncoderName(t)
 	typ := g.getType(t)
 
+	ptr, err := g.isPointerMarshaler(t)
+	if err != nil {
+		return fmt.Errorf("cannot generate encoder for %v: %v", t, err)
+	}
+	if ptr {
+		typ = "*" + typ
+	}
+
 	fmt.Fprintln(g.out, "func "+fname+"(info gocql.TypeInfo, in "+typ+") ([]byte, error) {")
 	fmt.Fprintln(g.out, "  udt, ok := info.(gocql.UDTTypeInfo)")
 	fmt.Fprintln(g.out, "  if !ok {")
-	fmt.Fprintf(g.out, "    return nil, fmt.Errorf(\"cannot marshal %%T to non-udt type %%s\", in, info)")
+	// The type is spelled out instead of formatting in with %T, which would make in escape to the heap.
+	inType := t.String()
+	if ptr {
+		inType = "*" + inType
+	}
+	fmt.Fprintf(g.out, "    return nil, fmt.Errorf(%q, info)\n", "cannot marshal "+strings.ReplaceAll(inType, "%", "%%")+" to non-udt type %s")
+	fmt.Fprintln(g.out, "  }")
+	fmt.Fprintln(g.out, "  if info.Version() < 3 {")
+	fmt.Fprintln(g.out, "    return nil, marshal.ErrorUDTUnavailable")
 	fmt.Fprintln(g.out, "  }")
 	fmt.Fprintln(g.out, "  var buf []byte")
 
-	fs, err := getStructFields(t)
+	fs, err := g.getStructFields(t)
 	if err != nil {
 		return fmt.Errorf("cannot generate encoder for %v: %v", t, err)
 	}
 
+	for _, f := range fs {
+		if err := g.genRequiredFieldSet(t, f); err != nil {
+			return err
+		}
+	}
+
 	fmt.Fprintln(g.out, "  for _, udtElement := range udt.Elements {")
 	fmt.Fprintln(g.out, "    switch udtElement.Name {")
 	firstCondition := true
@@ -187,21 +686,101 @@ func (g *Generator) genStructEncoder(t reflect.Type) error {
 		}
 	}
 
-	if g.disallowUnknownFields {
-		fmt.Fprintln(g.out, "    default:")
-		fmt.Fprintf(g.out, "      return fmt.Errorf(\"unknown field: %%s\", udtElement.Name)")
-	} else {
-		fmt.Fprintln(g.out, "    default:")
+	policy, captureField, err := g.getUnknownFieldsPolicy(t)
+	if err != nil {
+		return fmt.Errorf("cannot generate encoder for %v: %v", t, err)
+	}
+
+	fmt.Fprintln(g.out, "    default:")
+	switch policy {
+	case unknownFieldsError:
+		fmt.Fprintf(g.out, "      return nil, fmt.Errorf(\"unknown field: %%s\", udtElement.Name)\n")
+	case unknownFieldsCapture:
+		fmt.Fprintln(g.out, "      buf = marshal.AppendBytes(buf, in."+captureField+"[udtElement.Name])")
+	default:
 		fmt.Fprintln(g.out, "      buf = marshal.AppendBytes(buf, nil)")
 	}
 	fmt.Fprintln(g.out, "    }")
 	fmt.Fprintln(g.out, "  }")
+
+	// Required fields missing from the UDT would be silently lost.
+	for _, f := range fs {
+		if err := g.genRequiredFieldCheck(t, f, "nil, "); err != nil {
+			return err
+		}
+	}
+
 	fmt.Fprintln(g.out, "  return buf, nil")
 	fmt.Fprintln(g.out, "}")
 
 	return nil
 }
 
+// genStructUDTMarshaler generates MarshalUDT method implementing gocql.UDTMarshaler interface.
+func (g *Generator) genStructUDTMarshaler(t reflect.Type) error {
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+
+	typ := g.getType(t)
+
+	fs, err := g.getStructFields(t)
+	if err != nil {
+		return fmt.Errorf("cannot generate UDT marshaler for %v: %v", t, err)
+	}
+
+	ptr, err := g.isPointerMarshaler(t)
+	if err != nil {
+		return fmt.Errorf("cannot generate UDT marshaler for %v: %v", t, err)
+	}
+	if ptr {
+		typ = "*" + typ
+	}
+
+	fmt.Fprintln(g.out, "// MarshalUDT supports gocql.UDTMarshaler interface")
+	fmt.Fprintln(g.out, "func (v "+typ+") MarshalUDT(name string, info gocql.TypeInfo) ([]byte, error) {")
+	fmt.Fprintln(g.out, "  var buf []byte")
+	fmt.Fprintln(g.out, "  switch name {")
+	for _, f := range fs {
+		tags, err := parseFieldTags(f)
+		if err != nil {
+			return err
+		}
+		if tags.omit {
+			continue
+		}
+
+		fmt.Fprintf(g.out, "  case %s:\n", caseNames(g.getFieldNames(t, f, tags)))
+		if err := g.genRequiredValueCheck(t, f, "v."+f.Name, "nil, ", tags, 2); err != nil {
+			return err
+		}
+		if err := g.genTypeEncoder(f.Type, "info", "v."+f.Name, tags, 2, false); err != nil {
+			return err
+		}
+	}
+
+	policy, captureField, err := g.getUnknownFieldsPolicy(t)
+	if err != nil {
+		return fmt.Errorf("cannot generate UDT marshaler for %v: %v", t, err)
+	}
+
+	fmt.Fprintln(g.out, "  default:")
+	switch policy {
+	case unknownFieldsError:
+		fmt.Fprintf(g.out, "    return nil, fmt.Errorf(\"unknown field: %%s\", name)\n")
+	case unknownFieldsCapture:
+		fmt.Fprintln(g.out, "    return v."+captureField+"[name], nil")
+	default:
+		fmt.Fprintln(g.out, "    return nil, nil")
+	}
+	fmt.Fprintln(g.out, "  }")
+	fmt.Fprintln(g.out, "  data, _, err := marshal.ReadBytes2(buf)")
+	fmt.Fprintln(g.out, "  return data, err")
+	fmt.Fprintln(g.out, "}")
+
+	return nil
+}
+
 //nolint:dupl // this function is very similar to genStructUnmarshaler but does the opposite
 func (g *Generator) genStructMarshaler(t reflect.Type) error {
 	switch t.Kind() {
@@ -213,8 +792,26 @@ func (g *Generator) genStructMarshaler(t reflect.Type) error {
 	fname := g.getEncoderName(t)
 	typ := g.getType(t)
 
+	ptr, err := g.isPointerMarshaler(t)
+	if err != nil {
+		return fmt.Errorf("cannot generate marshaler for %v: %v", t, err)
+	}
+
 	fmt.Fprintln(g.out, "// MarshalCQL supports gocql.Marshaler interface")
-	fmt.Fprintln(g.out, "func (v "+typ+") MarshalCQL(info gocql.TypeInfo) ([]byte, error) {")
+	if ptr {
+		fmt.Fprintln(g.out, "func (v *"+typ+") MarshalCQL(info gocql.TypeInfo) ([]byte, error) {")
+		fmt.Fprintln(g.out, "  if v == nil {")
+		fmt.Fprintln(g.out, "    return nil, nil")
+		fmt.Fprintln(g.out, "  }")
+	} else {
+		fmt.Fprintln(g.out, "func (v "+typ+") MarshalCQL(info gocql.TypeInfo) ([]byte, error) {")
+	}
+	if implements(t, beforeMarshalerIface) {
+		g.imports["fmt"] = "fmt"
+		fmt.Fprintln(g.out, "  if err := v.BeforeMarshalCQL(); err != nil {")
+		fmt.Fprintf(g.out, "    return nil, fmt.Errorf(\"%s.BeforeMarshalCQL: %%w\", err)\n", t.Name())
+		fmt.Fprintln(g.out, "  }")
+	}
 	fmt.Fprintln(g.out, "  return "+fname+"(info, v)")
 	fmt.Fprintln(g.out, "}")
 
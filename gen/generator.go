@@ -107,6 +107,21 @@ type Generator struct {
 	// conservative mode
 	conservative bool
 
+	// generate marshalers of structs with pointer receivers
+	pointerMarshalers bool
+
+	// zero the struct before decoding instead of merging decoded fields into it
+	resetOnDecode bool
+
+	// generate MarshalUDT/UnmarshalUDT methods
+	udtMarshalers bool
+
+	// generate ScanCQL methods decoding rows of query results
+	rowScanners bool
+
+	// generate DiffCQL methods updating fields of non-frozen UDT columns
+	diffUpdates bool
+
 	// package path to local alias map for tracking imports
 	imports map[string]string
 
@@ -190,6 +205,37 @@ func (g *Generator) Conservative() {
 	g.conservative = true
 }
 
+// ResetOnDecode instructs generated decoders to zero the struct before decoding, so that fields missing from
+// the data are not left with stale values. By default, only fields present in the data are overwritten.
+func (g *Generator) ResetOnDecode() {
+	g.resetOnDecode = true
+}
+
+// PointerMarshalers instructs to generate MarshalCQL (and MarshalUDT) methods of structs with pointer receivers
+// to avoid copying large structs. Only a pointer to the struct then implements gocql.Marshaler, so the pointer
+// has to be passed to gocql.
+func (g *Generator) PointerMarshalers() {
+	g.pointerMarshalers = true
+}
+
+// UDTMarshalers instructs to generate MarshalUDT/UnmarshalUDT methods implementing gocql.UDTMarshaler and
+// gocql.UDTUnmarshaler interfaces in addition to MarshalCQL/UnmarshalCQL.
+func (g *Generator) UDTMarshalers() {
+	g.udtMarshalers = true
+}
+
+// RowScanners instructs to generate ScanCQL methods of structs decoding whole rows of query results,
+// see easycql.RowScanner.
+func (g *Generator) RowScanners() {
+	g.rowScanners = true
+}
+
+// DiffUpdates instructs to generate DiffCQL methods of structs returning assignments of changed fields
+// of non-frozen UDT columns.
+func (g *Generator) DiffUpdates() {
+	g.diffUpdates = true
+}
+
 // addTypes requests to generate encoding/decoding funcs for the given type.
 func (g *Generator) addType(t reflect.Type) {
 	if g.typesSeen[t] {
@@ -285,18 +331,62 @@ func (g *Generator) Run(out io.Writer) error {
 		if err := g.genStructUnmarshaler(t); err != nil {
 			return err
 		}
+		if err := g.genStructFieldState(t); err != nil {
+			return err
+		}
+		if err := g.genStructStatements(t); err != nil {
+			return err
+		}
+		if g.rowScanners {
+			if err := g.genStructScanner(t); err != nil {
+				return err
+			}
+		}
+		if g.diffUpdates {
+			if err := g.genStructDiff(t); err != nil {
+				return err
+			}
+		}
+
+		if !g.udtMarshalers {
+			continue
+		}
+
+		if err := g.genStructUDTMarshaler(t); err != nil {
+			return err
+		}
+		if err := g.genStructUDTUnmarshaler(t); err != nil {
+			return err
+		}
 	}
 	g.printHeader()
 	_, err := out.Write(g.out.Bytes())
 	return err
 }
 
+// getFieldName returns the CQL name of the field. If the field has multiple names, the first one is returned.
 //nolint:gocritic // parameter f is huge
 func (g *Generator) getFieldName(t reflect.Type, f reflect.StructField, tags fieldTags) string {
-	if tags.name != "" {
-		return tags.name
+	return g.getFieldNames(t, f, tags)[0]
+}
+
+// getFieldNames returns all CQL names the field accepts, names are separated by | in the tag.
+//nolint:gocritic // parameter f is huge
+func (g *Generator) getFieldNames(t reflect.Type, f reflect.StructField, tags fieldTags) []string {
+	name := tags.name
+	if name == "" {
+		name = g.fieldNamer.GetCQLFieldName(t, f)
 	}
-	return g.fieldNamer.GetCQLFieldName(t, f)
+	return strings.Split(name, "|")
+}
+
+// caseNames returns a list of quoted names that can be used in a case clause of the generated code.
+func caseNames(names []string) string {
+	quoted := make([]string, len(names))
+	for i, name := range names {
+		quoted[i] = strconv.Quote(name)
+	}
+	return strings.Join(quoted, ", ")
 }
 
 // fixes vendored paths.
@@ -544,6 +634,7 @@ func (LowerCamelCaseFieldNamer) GetCQLFieldName(t reflect.Type, f reflect.Struct
 	return lowerFirst(f.Name)
 }
 
+
 // SnakeCaseFieldNamer implements CamelCase to snake_case conversion for fields names.
 type SnakeCaseFieldNamer struct{}
 
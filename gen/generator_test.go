@@ -1,8 +1,10 @@
 package gen
 
 import (
+	"bytes"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 )
@@ -104,7 +106,7 @@ func TestUseCQLTag(t *testing.T) {
 	g := NewGenerator("test")
 	g.UseSnakeCase()
 	typ := reflect.TypeOf((*TestTagsStruct)(nil)).Elem()
-	fields, err := getStructFields(typ)
+	fields, err := g.getStructFields(typ)
 	require.NoError(t, err)
 
 	expected := map[string]string{
@@ -124,3 +126,260 @@ func TestUseCQLTag(t *testing.T) {
 
 	require.Equal(t, expected, actual)
 }
+
+func TestParseDefaultValue(t *testing.T) {
+	t.Parallel()
+	for i, test := range []struct {
+		Type  reflect.Type
+		In    string
+		Out   string
+		Error bool
+	}{
+		{reflect.TypeOf(""), "a,b\"c", `"a,b\"c"`, false},
+		{reflect.TypeOf(true), "true", "true", false},
+		{reflect.TypeOf(true), "yes", "", true},
+		{reflect.TypeOf(int8(0)), "-128", "-128", false},
+		{reflect.TypeOf(int8(0)), "128", "", true},
+		{reflect.TypeOf(uint16(0)), "65535", "65535", false},
+		{reflect.TypeOf(uint16(0)), "-1", "", true},
+		{reflect.TypeOf(float64(0)), "1.5", "1.5", false},
+		{reflect.TypeOf((*int)(nil)), "5", "5", false},
+		{reflect.TypeOf(time.Duration(0)), "1s", "1000000000", false},
+		{reflect.TypeOf(time.Time{}), "now", "", true},
+	} {
+		got, err := parseDefaultValue(test.Type, test.In)
+		if test.Error {
+			if err == nil {
+				t.Errorf("[%d] parseDefaultValue(%v, %s) expected error", i, test.Type, test.In)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("[%d] parseDefaultValue(%v, %s) unexpected error: %v", i, test.Type, test.In, err)
+		}
+		if got != test.Out {
+			t.Errorf("[%d] parseDefaultValue(%v, %s) = %s; want %s", i, test.Type, test.In, got, test.Out)
+		}
+	}
+}
+
+type TestMultipleNamesStruct struct {
+	Single   string `easycql:"single"`
+	Multiple string `easycql:"new_name|old_name"`
+	Default  string
+}
+
+func TestGetFieldNames(t *testing.T) {
+	t.Parallel()
+	g := NewGenerator("test")
+	typ := reflect.TypeOf((*TestMultipleNamesStruct)(nil)).Elem()
+	fields, err := g.getStructFields(typ)
+	require.NoError(t, err)
+
+	expected := map[string][]string{
+		"Single":   {"single"},
+		"Multiple": {"new_name", "old_name"},
+		"Default":  {"Default"},
+	}
+
+	actual := make(map[string][]string)
+	for _, f := range fields {
+		tags, err := parseFieldTags(f)
+		require.NoError(t, err)
+		actual[f.Name] = g.getFieldNames(typ, f, tags)
+		require.Equal(t, actual[f.Name][0], g.getFieldName(typ, f, tags))
+	}
+
+	require.Equal(t, expected, actual)
+}
+
+func TestParseCQLType(t *testing.T) {
+	t.Parallel()
+	for _, test := range []struct {
+		in, out string
+		err     bool
+	}{
+		{in: "int", out: "int"},
+		{in: "list<int>", out: "list<int>"},
+		{in: "frozen<map<text,list<int>>>", out: "frozen<map<text,list<int>>>"},
+		{in: "map< text , frozen<set<bigint>> >", out: "map<text,frozen<set<bigint>>>"},
+		{in: "tuple<int,text,boolean>", out: "tuple<int,text,boolean>"},
+		{in: "frozen<address>", out: "frozen<address>"},
+		{in: "", err: true},
+		{in: "list", err: true},
+		{in: "map<text>", err: true},
+		{in: "list<int", err: true},
+		{in: "list<int>>", err: true},
+	} {
+		expr, err := parseCQLType(test.in)
+		if test.err {
+			require.Error(t, err, test.in)
+			continue
+		}
+		require.NoError(t, err, test.in)
+		require.Equal(t, test.out, expr.String())
+	}
+}
+
+type TestCQLTypeStruct struct {
+	Valid    map[string][]int16 `easycql:",type=frozen<map<text,list<smallint>>>"`
+	NotList  string             `easycql:",type=list<text>"`
+	BadElem  []bool             `easycql:",type=list<text>"`
+	BadValue map[string]string  `easycql:",type=map<text,boolean>"`
+}
+
+func TestCheckCQLType(t *testing.T) {
+	t.Parallel()
+	typ := reflect.TypeOf((*TestCQLTypeStruct)(nil)).Elem()
+
+	f, _ := typ.FieldByName("Valid")
+	tags, err := parseFieldTags(f)
+	require.NoError(t, err)
+	require.Equal(t, "frozen<map<text,list<smallint>>>", tags.cqlTypeExpr.String())
+
+	for _, name := range []string{"NotList", "BadElem", "BadValue"} {
+		f, _ := typ.FieldByName(name)
+		_, err := parseFieldTags(f)
+		require.Error(t, err, name)
+	}
+}
+
+type TestRawStruct struct {
+	Valid    []byte `easycql:",raw"`
+	NotBytes string `easycql:",raw"`
+}
+
+func TestParseRawTag(t *testing.T) {
+	t.Parallel()
+	typ := reflect.TypeOf((*TestRawStruct)(nil)).Elem()
+
+	f, _ := typ.FieldByName("Valid")
+	tags, err := parseFieldTags(f)
+	require.NoError(t, err)
+	require.True(t, tags.raw)
+
+	f, _ = typ.FieldByName("NotBytes")
+	_, err = parseFieldTags(f)
+	require.EqualError(t, err, "easycql tag raw of field NotBytes: got string; expected []byte")
+}
+
+func TestQuoteColumnName(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, "user_id2", quoteColumnName("user_id2"))
+	require.Equal(t, `"UserID"`, quoteColumnName("UserID"))
+	require.Equal(t, `"2fa"`, quoteColumnName("2fa"))
+	require.Equal(t, `"a""b"`, quoteColumnName(`a"b`))
+}
+
+type TestNoKeyStatementStruct struct {
+	_    struct{} `easycql:",table=t"`
+	Name string   `easycql:"name"`
+}
+
+func TestStatementColumnsWithoutKey(t *testing.T) {
+	t.Parallel()
+	g := NewGenerator("test")
+	_, _, err := g.getStatementColumns(reflect.TypeOf(TestNoKeyStatementStruct{}))
+	require.EqualError(t, err, "no partition key, tag fields of the key with pk")
+}
+
+type TestCounterTableStruct struct {
+	_     struct{} `easycql:",table=t,counters"`
+	ID    string   `easycql:"id,pk"`
+	Views int64    `easycql:"views,counter"`
+	Name  string   `easycql:"name"`
+}
+
+func TestCounterTableRejectsRegularFields(t *testing.T) {
+	t.Parallel()
+	g := NewGenerator("test")
+	err := g.genStructStatements(reflect.TypeOf(TestCounterTableStruct{}))
+	require.EqualError(t, err, "cannot generate statements for gen.TestCounterTableStruct: "+
+		"field Name of counter table must be tagged with counter")
+}
+
+type TestEmptyCounterTableStruct struct {
+	_  struct{} `easycql:",table=t,counters"`
+	ID string   `easycql:"id,pk"`
+}
+
+func TestCounterTableRequiresCounters(t *testing.T) {
+	t.Parallel()
+	g := NewGenerator("test")
+	err := g.genStructStatements(reflect.TypeOf(TestEmptyCounterTableStruct{}))
+	require.EqualError(t, err, "cannot generate statements for gen.TestEmptyCounterTableStruct: "+
+		"counter table t has no counter columns, tag them with counter")
+}
+
+type TestMetaTagsStruct struct {
+	Written     time.Time     `easycql:"price,writetime"`
+	TTL         time.Duration `easycql:"price,ttl"`
+	BadWritten  time.Duration `easycql:"price,writetime"`
+	BadTTL      string        `easycql:"price,ttl"`
+	BadConflict int64         `easycql:"price,writetime,ttl"`
+}
+
+func TestParseMetaTags(t *testing.T) {
+	t.Parallel()
+	typ := reflect.TypeOf((*TestMetaTagsStruct)(nil)).Elem()
+
+	for _, name := range []string{"Written", "TTL"} {
+		f, _ := typ.FieldByName(name)
+		_, err := parseFieldTags(f)
+		require.NoError(t, err, name)
+	}
+	for _, name := range []string{"BadWritten", "BadTTL", "BadConflict"} {
+		f, _ := typ.FieldByName(name)
+		_, err := parseFieldTags(f)
+		require.Error(t, err, name)
+	}
+}
+
+type TestMetaKeyStruct struct {
+	_       struct{}  `easycql:",table=t"`
+	ID      string    `easycql:"id,pk"`
+	Written time.Time `easycql:"id,writetime"`
+}
+
+type TestMetaUnknownStruct struct {
+	_   struct{}      `easycql:",table=t"`
+	ID  string        `easycql:"id,pk"`
+	TTL time.Duration `easycql:"price,ttl"`
+}
+
+type TestMetaCollectionStruct struct {
+	_       struct{}  `easycql:",table=t"`
+	ID      string    `easycql:"id,pk"`
+	Tags    []string  `easycql:"tags"`
+	Frozen  []string  `easycql:"frozen,type=frozen<list<text>>"`
+	Written time.Time `easycql:"tags,writetime"`
+}
+
+type TestMetaFrozenStruct struct {
+	_       struct{}  `easycql:",table=t"`
+	ID      string    `easycql:"id,pk"`
+	Frozen  []string  `easycql:"frozen,type=frozen<list<text>>"`
+	Data    []byte    `easycql:"data"`
+	Written time.Time `easycql:"frozen,writetime"`
+	DataWritten time.Time `easycql:"data,writetime"`
+}
+
+func TestMetaColumnValidation(t *testing.T) {
+	t.Parallel()
+	g := NewGenerator("test")
+	err := g.genStructStatements(reflect.TypeOf(TestMetaKeyStruct{}))
+	require.EqualError(t, err, "cannot generate statements for gen.TestMetaKeyStruct: "+
+		"field Written: writetime() of primary key column id is not supported")
+
+	err = g.genStructStatements(reflect.TypeOf(TestMetaUnknownStruct{}))
+	require.EqualError(t, err, "cannot generate statements for gen.TestMetaUnknownStruct: "+
+		"field TTL: ttl() of unknown column price")
+
+	err = g.genStructStatements(reflect.TypeOf(TestMetaCollectionStruct{}))
+	require.EqualError(t, err, "cannot generate statements for gen.TestMetaCollectionStruct: "+
+		"field Written: writetime() of non-frozen collection column tags is not supported")
+
+	g.out = &bytes.Buffer{}
+	require.NoError(t, g.genStructStatements(reflect.TypeOf(TestMetaFrozenStruct{})))
+}
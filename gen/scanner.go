@@ -0,0 +1,227 @@
+package gen
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// genStructScanner generates ScanCQL method decoding a row of query results into the struct. Columns are mapped
+// to fields by name the same way as UDT elements.
+func (g *Generator) genStructScanner(t reflect.Type) error {
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+
+	typ := g.getType(t)
+
+	fs, err := g.getStructFields(t)
+	if err != nil {
+		return fmt.Errorf("cannot generate row scanner for %v: %v", t, err)
+	}
+
+	presenceField, err := getPresenceField(t)
+	if err != nil {
+		return fmt.Errorf("cannot generate row scanner for %v: %v", t, err)
+	}
+
+	reset, err := g.isResetOnDecode(t)
+	if err != nil {
+		return fmt.Errorf("cannot generate row scanner for %v: %v", t, err)
+	}
+
+	g.imports["fmt"] = "fmt"
+
+	fmt.Fprintln(g.out, "// ScanCQL decodes a row with the given columns and their serialized values into v.")
+	fmt.Fprintln(g.out, "func (v *"+typ+") ScanCQL(columns []gocql.ColumnInfo, values [][]byte) error {")
+	fmt.Fprintln(g.out, "  if len(columns) != len(values) {")
+	fmt.Fprintf(g.out, "    return fmt.Errorf(\"got %%d values for %%d columns\", len(values), len(columns))\n")
+	fmt.Fprintln(g.out, "  }")
+	if reset {
+		fmt.Fprintln(g.out, "  *v = "+typ+"{}")
+	}
+	if presenceField != "" {
+		fmt.Fprintf(g.out, "  v.%s.Reset(%d)\n", presenceField, len(fs))
+	}
+
+	// Init embedded pointer fields.
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.Anonymous || f.Type.Kind() != reflect.Ptr {
+			continue
+		}
+		fmt.Fprintln(g.out, "  v."+f.Name+" = new("+g.getType(f.Type.Elem())+")")
+	}
+
+	for _, f := range fs {
+		if err := g.genRequiredFieldSet(t, f); err != nil {
+			return err
+		}
+	}
+
+	for _, f := range fs {
+		if err := g.genDefaultFieldValue(f, "v."+f.Name, reset); err != nil {
+			return err
+		}
+	}
+
+	fmt.Fprintln(g.out, "  for i, column := range columns {")
+	fmt.Fprintln(g.out, "    data := values[i]")
+	fmt.Fprintln(g.out, "    switch column.Name {")
+	for i, f := range fs {
+		tags, err := parseFieldTags(f)
+		if err != nil {
+			return err
+		}
+		if tags.omit {
+			continue
+		}
+
+		fmt.Fprintf(g.out, "    case %s:\n", caseNames(g.getFieldNames(t, f, tags)))
+		if presenceField != "" {
+			fmt.Fprintf(g.out, "      v.%s.Record(%d, data)\n", presenceField, i)
+		}
+		if tags.notNull {
+			fmt.Fprintln(g.out, "      if data == nil {")
+			fmt.Fprintf(g.out, "        return fmt.Errorf(\"column %%s: null value not allowed\", column.Name)\n")
+			fmt.Fprintln(g.out, "      }")
+			tags.defaultSet = false
+		}
+		if err := g.genTypeDecoder(f.Type, "column.TypeInfo", "data", "v."+f.Name, tags, 3); err != nil {
+			return err
+		}
+		if tags.required {
+			fmt.Fprintf(g.out, "      %s = true\n", setVarName(f))
+		}
+	}
+
+	metaFields, metaTags, err := g.getMetaFields(t)
+	if err != nil {
+		return fmt.Errorf("cannot generate row scanner for %v: %v", t, err)
+	}
+	for i, f := range metaFields {
+		name := metaFunction(metaTags[i]) + "(" + g.getFieldName(t, f, metaTags[i]) + ")"
+		fmt.Fprintf(g.out, "    case %s:\n", caseNames([]string{name}))
+		g.genMetaFieldDecoder(f.Type, "data", "v."+f.Name, metaTags[i], 3)
+	}
+
+	policy, captureField, err := g.getUnknownFieldsPolicy(t)
+	if err != nil {
+		return fmt.Errorf("cannot generate row scanner for %v: %v", t, err)
+	}
+	g.genUnknownFieldDecoder(policy, "v."+captureField, "column.Name", "data", 2)
+	fmt.Fprintln(g.out, "    }")
+	fmt.Fprintln(g.out, "  }")
+
+	for _, f := range fs {
+		if err := g.genRequiredFieldCheck(t, f, ""); err != nil {
+			return err
+		}
+	}
+
+	if implements(t, afterUnmarshalerIface) {
+		fmt.Fprintln(g.out, "  if err := v.AfterUnmarshalCQL(); err != nil {")
+		fmt.Fprintf(g.out, "    return fmt.Errorf(\"%s.AfterUnmarshalCQL: %%w\", err)\n", t.Name())
+		fmt.Fprintln(g.out, "  }")
+	}
+	fmt.Fprintln(g.out, "  return nil")
+	fmt.Fprintln(g.out, "}")
+
+	return nil
+}
+
+// getMetaFields returns fields of struct t holding writetime() or ttl() of columns. Like getStructFields, fields
+// of anonymous embedded structs and fields tagged with inline are walked too, column names referenced from inlined
+// structs get the inline prefix.
+func (g *Generator) getMetaFields(t reflect.Type) ([]reflect.StructField, []fieldTags, error) {
+	var fields []reflect.StructField
+	var fieldsTags []fieldTags
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tags, err := parseFieldTags(f)
+		if err != nil {
+			return nil, nil, err
+		}
+		if tags.omit {
+			continue
+		}
+		if tags.writetime || tags.ttl {
+			fields = append(fields, f)
+			fieldsTags = append(fieldsTags, tags)
+			continue
+		}
+
+		t1 := f.Type
+		if f.Anonymous && t1.Kind() == reflect.Ptr {
+			t1 = t1.Elem()
+		}
+		if t1.Kind() != reflect.Struct || !(f.Anonymous && tags.name == "" || tags.inline) {
+			continue
+		}
+
+		fs, fsTags, err := g.getMetaFields(t1)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error processing field %s: %v", f.Name, err)
+		}
+		if !tags.inline {
+			// Fields of embedded structs are promoted, so they are accessed by their own names.
+			fields = append(fields, fs...)
+			fieldsTags = append(fieldsTags, fsTags...)
+			continue
+		}
+		for j, f1 := range fs {
+			f1.Tag = replaceTagName(f1.Tag, tags.inlinePrefix+g.getFieldName(t1, f1, fsTags[j]))
+			f1.Name = f.Name + "." + f1.Name
+			f1.Index = append(append([]int(nil), f.Index...), f1.Index...)
+			tags1, err := parseFieldTags(f1)
+			if err != nil {
+				return nil, nil, err
+			}
+			fields = append(fields, f1)
+			fieldsTags = append(fieldsTags, tags1)
+		}
+	}
+	return fields, fieldsTags, nil
+}
+
+// metaFunction returns the CQL function selecting the meta field, either writetime or ttl.
+func metaFunction(tags fieldTags) string {
+	if tags.writetime {
+		return "writetime"
+	}
+	return "ttl"
+}
+
+// genMetaFieldDecoder generates code that decodes writetime() or ttl() result in into out of type t.
+// writetime() is bigint in microseconds since Unix epoch, ttl() is int in seconds. Null is decoded as zero value.
+func (g *Generator) genMetaFieldDecoder(t reflect.Type, in, out string, tags fieldTags, indent int) {
+	ws := strings.Repeat("  ", indent)
+
+	size, dec := "4", "marshal.DecInt("+in+")"
+	if tags.writetime {
+		size, dec = "8", "marshal.DecBigInt("+in+")"
+	}
+
+	fmt.Fprintln(g.out, ws+"switch {")
+	fmt.Fprintln(g.out, ws+"case "+in+" == nil:")
+	if t == timeType {
+		fmt.Fprintln(g.out, ws+"  "+out+" = "+g.getType(t)+"{}")
+	} else {
+		fmt.Fprintln(g.out, ws+"  "+out+" = 0")
+	}
+	fmt.Fprintln(g.out, ws+"case len("+in+") != "+size+":")
+	fmt.Fprintf(g.out, "%s  return fmt.Errorf(\"column %%s: expected %s bytes, got %%d\", column.Name, len(%s))\n",
+		ws, size, in)
+	fmt.Fprintln(g.out, ws+"default:")
+	switch {
+	case t == timeType:
+		us := g.uniqueVarName()
+		fmt.Fprintln(g.out, ws+"  "+us+" := "+dec)
+		fmt.Fprintln(g.out, ws+"  "+out+" = "+g.pkgAlias("time")+".Unix("+us+"/1000000, "+us+"%1000000*1000).UTC()")
+	case t == durationType:
+		fmt.Fprintln(g.out, ws+"  "+out+" = "+g.pkgAlias("time")+".Duration("+dec+") * "+g.pkgAlias("time")+".Second")
+	default:
+		fmt.Fprintln(g.out, ws+"  "+out+" = "+g.getType(t)+"("+dec+")")
+	}
+	fmt.Fprintln(g.out, ws+"}")
+}
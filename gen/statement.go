@@ -0,0 +1,445 @@
+package gen
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/gocql/gocql"
+)
+
+// getTable returns the table name of struct t from the table option or an empty string.
+func getTable(t reflect.Type) (string, error) {
+	var table string
+	for i := 0; i < t.NumField(); i++ {
+		tags, err := parseFieldTags(t.Field(i))
+		if err != nil {
+			return "", err
+		}
+		if tags.table == "" {
+			continue
+		}
+		if table != "" {
+			return "", fmt.Errorf("multiple tables %s and %s", table, tags.table)
+		}
+		table = tags.table
+	}
+	return table, nil
+}
+
+// isCounterTable returns whether struct t is mapped to a counter table by counters option.
+func isCounterTable(t reflect.Type) (bool, error) {
+	for i := 0; i < t.NumField(); i++ {
+		tags, err := parseFieldTags(t.Field(i))
+		if err != nil {
+			return false, err
+		}
+		if tags.counters {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// statementColumn is a column of the table mapped to a struct field.
+type statementColumn struct {
+	name  string
+	field reflect.StructField
+	tags  fieldTags
+}
+
+// quoteColumnName returns the column name as used in CQL statements. Unquoted names are case-insensitive in CQL,
+// so names that are not lowercase identifiers are quoted to match the column the codecs match.
+func quoteColumnName(name string) string {
+	for i, c := range name {
+		if !(c >= 'a' && c <= 'z' || c == '_' || i > 0 && c >= '0' && c <= '9') {
+			return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+		}
+	}
+	return name
+}
+
+// getStatementColumns returns regular columns and primary key columns of the table mapped to struct t.
+// Partition key columns precede clustering columns.
+func (g *Generator) getStatementColumns(t reflect.Type) (regular, key []statementColumn, err error) {
+	fs, err := g.getStructFields(t)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var partition, clustering []statementColumn
+	for _, f := range fs {
+		tags, err := parseFieldTags(f)
+		if err != nil {
+			return nil, nil, err
+		}
+		if tags.omit {
+			continue
+		}
+
+		c := statementColumn{name: quoteColumnName(g.getFieldName(t, f, tags)), field: f, tags: tags}
+		switch {
+		case tags.partitionKey && tags.clusteringKey:
+			return nil, nil, fmt.Errorf("field %s cannot be both partition and clustering key", f.Name)
+		case tags.partitionKey:
+			partition = append(partition, c)
+		case tags.clusteringKey:
+			clustering = append(clustering, c)
+		default:
+			regular = append(regular, c)
+		}
+	}
+
+	if len(partition) == 0 {
+		return nil, nil, fmt.Errorf("no partition key, tag fields of the key with pk")
+	}
+	return regular, append(partition, clustering...), nil
+}
+
+// isCollectionColumn returns whether column c holds a non-frozen collection. The kind of the column is taken
+// from the CQL type in tags, or from the Go type of the field when it is not given.
+func isCollectionColumn(c statementColumn) bool {
+	if c.tags.cqlTypeExpr != nil {
+		return c.tags.cqlTypeExpr.isCollection() && !c.tags.cqlTypeExpr.frozen
+	}
+	if c.tags.cqlTypeSet {
+		return c.tags.cqlType == gocql.TypeList || c.tags.cqlType == gocql.TypeSet || c.tags.cqlType == gocql.TypeMap
+	}
+	if c.tags.codec != "" || c.tags.raw {
+		return false
+	}
+	t := c.field.Type
+	switch t.Kind() {
+	case reflect.Slice:
+		return t.Elem().Kind() != reflect.Uint8
+	case reflect.Array, reflect.Map:
+		return true
+	}
+	return false
+}
+
+// checkMetaColumn checks that writetime() or ttl() selected by a field with tags can be applied to column name.
+// Cassandra rejects them for primary key columns and non-frozen collections.
+func checkMetaColumn(name string, tags fieldTags, regular, key []statementColumn) error {
+	for _, c := range key {
+		if c.name == name {
+			return fmt.Errorf("%s() of primary key column %s is not supported", metaFunction(tags), name)
+		}
+	}
+	for _, c := range regular {
+		if c.name != name {
+			continue
+		}
+		if isCollectionColumn(c) {
+			return fmt.Errorf("%s() of non-frozen collection column %s is not supported", metaFunction(tags), name)
+		}
+		return nil
+	}
+	return fmt.Errorf("%s() of unknown column %s", metaFunction(tags), name)
+}
+
+// keyCondition returns the WHERE condition of statements selecting a row by the primary key.
+func keyCondition(key []statementColumn) string {
+	conditions := make([]string, len(key))
+	for i, c := range key {
+		conditions[i] = c.name + " = ?"
+	}
+	return strings.Join(conditions, " AND ")
+}
+
+// genStructStatements generates methods returning CQL statements of the table mapped to struct t and their
+// bind values.
+func (g *Generator) genStructStatements(t reflect.Type) error {
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+
+	table, err := getTable(t)
+	if err != nil {
+		return fmt.Errorf("cannot generate statements for %v: %v", t, err)
+	}
+	if table == "" {
+		return nil
+	}
+
+	regular, key, err := g.getStatementColumns(t)
+	if err != nil {
+		return fmt.Errorf("cannot generate statements for %v: %v", t, err)
+	}
+
+	typ := g.getType(t)
+	ptr, err := g.isPointerMarshaler(t)
+	if err != nil {
+		return fmt.Errorf("cannot generate statements for %v: %v", t, err)
+	}
+	if ptr {
+		typ = "*" + typ
+	}
+
+	all := append(append([]statementColumn{}, regular...), key...)
+	names := make([]string, len(all))
+	for i, c := range all {
+		names[i] = c.name
+	}
+
+	metaFields, metaTags, err := g.getMetaFields(t)
+	if err != nil {
+		return fmt.Errorf("cannot generate statements for %v: %v", t, err)
+	}
+	for i, f := range metaFields {
+		name := quoteColumnName(g.getFieldName(t, f, metaTags[i]))
+		if err := checkMetaColumn(name, metaTags[i], regular, key); err != nil {
+			return fmt.Errorf("cannot generate statements for %v: field %s: %v", t, f.Name, err)
+		}
+		names = append(names, metaFunction(metaTags[i])+"("+name+")")
+	}
+
+	counters, err := isCounterTable(t)
+	if err != nil {
+		return fmt.Errorf("cannot generate statements for %v: %v", t, err)
+	}
+	if counters {
+		err = g.genCounterIncrement(t, typ, table, regular, key)
+	} else {
+		err = g.genWriteStatements(typ, table, regular, key)
+	}
+	if err != nil {
+		return fmt.Errorf("cannot generate statements for %v: %v", t, err)
+	}
+
+	fmt.Fprintln(g.out, "// SelectByKeyCQL returns SELECT statement of the row, KeyBindValues returns its bind values.")
+	fmt.Fprintln(g.out, "func (v "+typ+") SelectByKeyCQL() string {")
+	fmt.Fprintf(g.out, "  return %q\n", "SELECT "+strings.Join(names, ", ")+" FROM "+table+" WHERE "+keyCondition(key))
+	fmt.Fprintln(g.out, "}")
+
+	fmt.Fprintln(g.out, "// DeleteCQL returns DELETE statement of the row, KeyBindValues returns its bind values.")
+	fmt.Fprintln(g.out, "func (v "+typ+") DeleteCQL() string {")
+	fmt.Fprintf(g.out, "  return %q\n", "DELETE FROM "+table+" WHERE "+keyCondition(key))
+	fmt.Fprintln(g.out, "}")
+
+	fmt.Fprintln(g.out, "// KeyBindValues returns values of the primary key columns of SelectByKeyCQL and DeleteCQL statements.")
+	fmt.Fprintln(g.out, "func (v "+typ+") KeyBindValues() []interface{} {")
+	if err := g.genBindValues(key); err != nil {
+		return err
+	}
+	fmt.Fprintln(g.out, "}")
+
+	return nil
+}
+
+// genWriteStatements generates methods returning INSERT and UPDATE statements of the table with regular and key
+// columns and their bind values.
+func (g *Generator) genWriteStatements(typ, table string, regular, key []statementColumn) error {
+	all := append(append([]statementColumn{}, regular...), key...)
+	names := make([]string, len(all))
+	for i, c := range all {
+		names[i] = c.name
+	}
+
+	insert := "INSERT INTO " + table + " (" + strings.Join(names, ", ") + ") VALUES (" +
+		strings.TrimSuffix(strings.Repeat("?, ", len(all)), ", ") + ")"
+	fmt.Fprintln(g.out, "// InsertCQL returns INSERT statement of the row, BindValues returns its bind values")
+	fmt.Fprintln(g.out, "// followed by values of the USING clause.")
+	fmt.Fprintln(g.out, "func (v "+typ+") InsertCQL(using easycql.Using) string {")
+	fmt.Fprintf(g.out, "  return %q + using.Clause()\n", insert)
+	fmt.Fprintln(g.out, "}")
+
+	fmt.Fprintln(g.out, "// UpdateCQL returns UPDATE statement of the row, values of the USING clause followed by")
+	fmt.Fprintln(g.out, "// BindValues are its bind values. The statement is empty when the table has no regular columns.")
+	fmt.Fprintln(g.out, "func (v "+typ+") UpdateCQL(using easycql.Using) string {")
+	if len(regular) == 0 {
+		// Primary key columns cannot be updated, there is nothing to set.
+		fmt.Fprintln(g.out, "  return \"\"")
+	} else {
+		assignments := make([]string, len(regular))
+		for i, c := range regular {
+			assignments[i] = c.name + " = ?"
+		}
+		fmt.Fprintf(g.out, "  return %q + using.Clause() + %q\n", "UPDATE "+table,
+			" SET "+strings.Join(assignments, ", ")+" WHERE "+keyCondition(key))
+	}
+	fmt.Fprintln(g.out, "}")
+
+	fmt.Fprintln(g.out, "// BindValues returns values of the columns of InsertCQL and UpdateCQL statements.")
+	fmt.Fprintln(g.out, "func (v "+typ+") BindValues() []interface{} {")
+	if err := g.genBindValues(all); err != nil {
+		return err
+	}
+	fmt.Fprintln(g.out, "}")
+
+	for _, c := range regular {
+		g.genCollectionMutations(typ, c)
+	}
+	return nil
+}
+
+// genBindValues generates code returning the bind values of columns cs.
+func (g *Generator) genBindValues(cs []statementColumn) error {
+	values := make([]string, len(cs))
+	for i, c := range cs {
+		value, err := g.genBindValue(c, 1)
+		if err != nil {
+			return err
+		}
+		values[i] = value
+	}
+
+	fmt.Fprintln(g.out, "  return []interface{}{")
+	for _, value := range values {
+		fmt.Fprintln(g.out, "    "+value+",")
+	}
+	fmt.Fprintln(g.out, "  }")
+	return nil
+}
+
+// genBindValue generates code preparing the bind value of column c and returns the expression of the value.
+// Fields with options changing the encoding are bound through easycql.MarshalerFunc marshaling them the same
+// way as the generated encoder, others are passed to gocql directly.
+func (g *Generator) genBindValue(c statementColumn, indent int) (string, error) {
+	in := "v." + c.field.Name
+	if c.tags.codec == "" && !c.tags.omitEmpty && !c.tags.raw {
+		return g.genMarshalerValue(c.field.Type, in, indent), nil
+	}
+
+	// The closure captures a copy of the field, capturing the receiver would move it to the heap.
+	ws := strings.Repeat("  ", indent)
+	field := g.uniqueVarName()
+	value := g.uniqueVarName()
+	fmt.Fprintln(g.out, ws+field+" := "+in)
+	fmt.Fprintln(g.out, ws+value+" := easycql.MarshalerFunc(func(info gocql.TypeInfo) ([]byte, error) {")
+	if c.tags.omitEmpty {
+		cond, err := g.emptyCondition(c.field.Type, field)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintln(g.out, ws+"  if "+cond+" {")
+		fmt.Fprintln(g.out, ws+"    return nil, nil")
+		fmt.Fprintln(g.out, ws+"  }")
+	}
+	switch {
+	case c.tags.raw:
+		fmt.Fprintln(g.out, ws+"  return []byte("+field+"), nil")
+	case c.tags.codec != "":
+		fmt.Fprintln(g.out, ws+"  return "+g.codecFuncName(c.tags.codec, "Encode")+"(info, "+field+")")
+	default:
+		fmt.Fprintln(g.out, ws+"  return "+g.genMarshalCall(c.field.Type, "info", field, indent+1))
+	}
+	fmt.Fprintln(g.out, ws+"})")
+	return value, nil
+}
+
+// genCollectionMutations generates methods returning fragments of UPDATE and DELETE statements that modify
+// elements of collection column c, and their bind values. Kind of the collection is taken from the CQL type
+// in tags, methods of both lists and sets are generated for slices without it. Frozen collections can only be
+// replaced as a whole, so no methods are generated for them. The methods don't depend on the row, they have
+// pointer receivers so that calling them doesn't copy it.
+func (g *Generator) genCollectionMutations(typ string, c statementColumn) {
+	t := c.field.Type
+	if c.tags.codec != "" || c.tags.raw || t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8 {
+		return
+	}
+	if c.tags.cqlTypeExpr != nil && c.tags.cqlTypeExpr.frozen {
+		return
+	}
+
+	name := strings.ReplaceAll(c.field.Name, ".", "")
+	switch t.Kind() {
+	case reflect.Slice, reflect.Array:
+		isList := !c.tags.cqlTypeSet || c.tags.cqlType == gocql.TypeList
+		isSet := !c.tags.cqlTypeSet || c.tags.cqlType == gocql.TypeSet
+		if !isList && !isSet {
+			return
+		}
+
+		elems := "elems ..." + g.getType(t.Elem())
+		if isList {
+			fmt.Fprintf(g.out, "// Append%sCQL returns assignment appending elems to list column %s and its bind values.\n",
+				name, c.name)
+			fmt.Fprintln(g.out, "func (v *"+typ+") Append"+name+"CQL("+elems+") (string, []interface{}) {")
+			fmt.Fprintf(g.out, "  return %q, []interface{}{elems}\n", c.name+" = "+c.name+" + ?")
+			fmt.Fprintln(g.out, "}")
+
+			fmt.Fprintf(g.out, "// Prepend%sCQL returns assignment prepending elems to list column %s and its bind values.\n",
+				name, c.name)
+			fmt.Fprintln(g.out, "func (v *"+typ+") Prepend"+name+"CQL("+elems+") (string, []interface{}) {")
+			fmt.Fprintf(g.out, "  return %q, []interface{}{elems}\n", c.name+" = ? + "+c.name)
+			fmt.Fprintln(g.out, "}")
+		}
+		if isSet {
+			fmt.Fprintf(g.out, "// Add%sCQL returns assignment adding elems to set column %s and its bind values.\n",
+				name, c.name)
+			fmt.Fprintln(g.out, "func (v *"+typ+") Add"+name+"CQL("+elems+") (string, []interface{}) {")
+			fmt.Fprintf(g.out, "  return %q, []interface{}{elems}\n", c.name+" = "+c.name+" + ?")
+			fmt.Fprintln(g.out, "}")
+		}
+		fmt.Fprintf(g.out, "// Remove%sCQL returns assignment removing all occurrences of elems from column %s and its\n",
+			name, c.name)
+		fmt.Fprintln(g.out, "// bind values.")
+		fmt.Fprintln(g.out, "func (v *"+typ+") Remove"+name+"CQL("+elems+") (string, []interface{}) {")
+		fmt.Fprintf(g.out, "  return %q, []interface{}{elems}\n", c.name+" = "+c.name+" - ?")
+		fmt.Fprintln(g.out, "}")
+	case reflect.Map:
+		key := g.getType(t.Key())
+		fmt.Fprintf(g.out, "// Put%sCQL returns assignment setting entry of map column %s and its bind values.\n",
+			name, c.name)
+		fmt.Fprintln(g.out, "func (v *"+typ+") Put"+name+"CQL(key "+key+", value "+g.getType(t.Elem())+
+			") (string, []interface{}) {")
+		fmt.Fprintf(g.out, "  return %q, []interface{}{key, value}\n", c.name+"[?] = ?")
+		fmt.Fprintln(g.out, "}")
+
+		fmt.Fprintf(g.out, "// Delete%sCQL returns DELETE selector of entry of map column %s and its bind values.\n",
+			name, c.name)
+		fmt.Fprintln(g.out, "func (v *"+typ+") Delete"+name+"CQL(key "+key+") (string, []interface{}) {")
+		fmt.Fprintf(g.out, "  return %q, []interface{}{key}\n", c.name+"[?]")
+		fmt.Fprintln(g.out, "}")
+	}
+}
+
+// genCounterIncrement generates delta type of counter columns of struct t and IncrementCQL method returning
+// UPDATE statement incrementing the counters. Counter tables can't be written by INSERT, so it replaces
+// the write statements.
+func (g *Generator) genCounterIncrement(t reflect.Type, typ, table string, regular, key []statementColumn) error {
+	if len(regular) == 0 {
+		return fmt.Errorf("counter table %s has no counter columns, tag them with counter", table)
+	}
+	for _, c := range regular {
+		if !c.tags.cqlTypeSet || c.tags.cqlType != gocql.TypeCounter {
+			return fmt.Errorf("field %s of counter table must be tagged with counter", c.field.Name)
+		}
+		switch c.field.Type.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		default:
+			return fmt.Errorf("counter field %s: got %v; expected a signed integer", c.field.Name, c.field.Type)
+		}
+	}
+
+	g.imports["strings"] = "strings"
+
+	delta := t.Name() + "Delta"
+	fmt.Fprintf(g.out, "// %s holds increments of counter columns of %s.\n", delta, t.Name())
+	fmt.Fprintln(g.out, "type "+delta+" struct {")
+	for _, c := range regular {
+		fmt.Fprintln(g.out, "  "+strings.ReplaceAll(c.field.Name, ".", "")+" int64")
+	}
+	fmt.Fprintln(g.out, "}")
+
+	fmt.Fprintln(g.out, "// IncrementCQL returns UPDATE statement incrementing counters of the row by non-zero fields of delta,")
+	fmt.Fprintln(g.out, "// and its bind values. The statement is empty when all fields of delta are zero.")
+	fmt.Fprintln(g.out, "func (v "+typ+") IncrementCQL(delta "+delta+") (string, []interface{}) {")
+	fmt.Fprintln(g.out, "  var assignments []string")
+	fmt.Fprintln(g.out, "  var values []interface{}")
+	for _, c := range regular {
+		field := "delta." + strings.ReplaceAll(c.field.Name, ".", "")
+		fmt.Fprintln(g.out, "  if "+field+" != 0 {")
+		fmt.Fprintf(g.out, "    assignments = append(assignments, %q)\n", c.name+" = "+c.name+" + ?")
+		fmt.Fprintln(g.out, "    values = append(values, "+field+")")
+		fmt.Fprintln(g.out, "  }")
+	}
+	fmt.Fprintln(g.out, "  if len(assignments) == 0 {")
+	fmt.Fprintln(g.out, "    return \"\", nil")
+	fmt.Fprintln(g.out, "  }")
+	fmt.Fprintf(g.out, "  return %q + strings.Join(assignments, \", \") + %q, append(values, v.KeyBindValues()...)\n",
+		"UPDATE "+table+" SET ", " WHERE "+keyCondition(key))
+	fmt.Fprintln(g.out, "}")
+	return nil
+}
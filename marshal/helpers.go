@@ -4,6 +4,11 @@
 
 package marshal
 
+import (
+	"errors"
+	"math"
+)
+
 func AppendBytes(p, d []byte) []byte {
 	if d == nil {
 		return appendInt(p, -1)
@@ -19,3 +24,27 @@ func appendInt(p []byte, n int32) []byte {
 		byte(n>>8),
 		byte(n))
 }
+
+// AppendCollectionSize appends the size of a collection encoded for the given protocol version to p.
+// Protocols older than version 3 use 2 bytes for collection sizes, newer protocols use 4 bytes.
+func AppendCollectionSize(version byte, p []byte, n int) ([]byte, error) {
+	if version > protoVersion2 {
+		if n > math.MaxInt32 {
+			return nil, errors.New("marshal: collection too large")
+		}
+		return appendInt(p, int32(n)), nil
+	}
+	if n > math.MaxUint16 {
+		return nil, errors.New("marshal: collection too large")
+	}
+	return append(p, byte(n>>8), byte(n)), nil
+}
+
+// AppendCollectionBytes appends d prefixed by its size encoded for the given protocol version to p.
+func AppendCollectionBytes(version byte, p, d []byte) ([]byte, error) {
+	p, err := AppendCollectionSize(version, p, len(d))
+	if err != nil {
+		return nil, err
+	}
+	return append(p, d...), nil
+}
@@ -10,6 +10,8 @@ import (
 	"math/big"
 )
 
+const protoVersion2 = 0x02
+
 var (
 	bigOne = big.NewInt(1)
 	// ErrorUDTUnavailable is returned when easycql is used on protocol older than version 3.
@@ -111,6 +113,37 @@ func ReadBytes2(p []byte) (bytes, rest []byte, err error) {
 	return p[:size], p[size:], nil
 }
 
+// ReadCollectionSize decodes the size of a collection encoded for the given protocol version and returns rest of p.
+// Protocols older than version 3 use 2 bytes for collection sizes, newer protocols use 4 bytes.
+func ReadCollectionSize(version byte, p []byte) (size int, rest []byte, err error) {
+	if version > protoVersion2 {
+		if len(p) < 4 {
+			return 0, nil, errors.New("read collection size: unexpected eof")
+		}
+		return int(readInt(p)), p[4:], nil
+	}
+	if len(p) < 2 {
+		return 0, nil, errors.New("read collection size: unexpected eof")
+	}
+	return int(p[0])<<8 | int(p[1]), p[2:], nil
+}
+
+// ReadCollectionBytes decodes bytes of a collection item encoded for the given protocol version and returns rest of p.
+// Negative size is decoded as nil bytes.
+func ReadCollectionBytes(version byte, p []byte) (bytes, rest []byte, err error) {
+	size, p, err := ReadCollectionSize(version, p)
+	if err != nil {
+		return nil, nil, err
+	}
+	if size < 0 {
+		return nil, p, nil
+	}
+	if len(p) < size {
+		return nil, nil, fmt.Errorf("read collection bytes: expecting %d bytes, got %d", size, len(p))
+	}
+	return p[:size], p[size:], nil
+}
+
 func readInt(p []byte) int32 {
 	return int32(p[0])<<24 | int32(p[1])<<16 | int32(p[2])<<8 | int32(p[3])
 }
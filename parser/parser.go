@@ -5,8 +5,13 @@ import (
 	"go/ast"
 	"go/parser"
 	"go/token"
+	"go/types"
 	"os/exec"
+	"reflect"
+	"strconv"
 	"strings"
+
+	"github.com/kiwicom/easycql/gen"
 )
 
 const structComment = "easycql:cql"
@@ -16,6 +21,26 @@ type Parser struct {
 	PkgName     string
 	StructNames []string
 	AllStructs  bool
+
+	// TableStructs maps names of structs with table option in easycql tags to whether the table is
+	// a counter table.
+	TableStructs map[string]bool
+	// CounterFields maps names of structs of counter tables to names of their fields tagged with counter option.
+	CounterFields map[string][]string
+	// PresenceStructs are names of structs with a field tagged with presence option.
+	PresenceStructs map[string]bool
+	// CollectionFields maps names of structs of regular tables to their collection fields.
+	CollectionFields map[string][]CollectionField
+	// Imports maps names of packages referred to by types of CollectionFields to their import paths.
+	Imports map[string]string
+}
+
+// CollectionField is a field of a table struct holding a collection, which gets methods modifying its elements.
+type CollectionField struct {
+	Name string
+	// List and Set tell which mutations of a slice are generated, Key is the key type of a map.
+	List, Set bool
+	Key, Elem string
 }
 
 type visitor struct {
@@ -23,6 +48,8 @@ type visitor struct {
 
 	name     string
 	explicit bool
+	// imports maps package names of imports of the current file to their paths.
+	imports map[string]string
 }
 
 func (p *Parser) needType(comments string) bool {
@@ -40,6 +67,18 @@ func (v *visitor) Visit(n ast.Node) (w ast.Visitor) {
 		return v
 	case *ast.File:
 		v.PkgName = n.Name.String()
+		v.imports = make(map[string]string)
+		for _, spec := range n.Imports {
+			path, err := strconv.Unquote(spec.Path.Value)
+			if err != nil {
+				continue
+			}
+			name := path[strings.LastIndex(path, "/")+1:]
+			if spec.Name != nil {
+				name = spec.Name.Name
+			}
+			v.imports[name] = path
+		}
 		return v
 
 	case *ast.GenDecl:
@@ -60,11 +99,149 @@ func (v *visitor) Visit(n ast.Node) (w ast.Visitor) {
 		return v
 	case *ast.StructType:
 		v.StructNames = append(v.StructNames, v.name)
+		v.parseStructOptions(n)
 		return nil
 	}
 	return nil
 }
 
+// parseStructOptions records options of easycql tags of the struct that change which methods are generated.
+func (v *visitor) parseStructOptions(n *ast.StructType) {
+	var table, counters, presence bool
+	var counterFields []string
+	var collectionFields []CollectionField
+	for _, f := range n.Fields.List {
+		if f.Tag == nil {
+			continue
+		}
+		tag, err := strconv.Unquote(f.Tag.Value)
+		if err != nil {
+			continue
+		}
+		for i, option := range gen.SplitTagOptions(reflect.StructTag(tag).Get("easycql")) {
+			switch {
+			case i == 0:
+			case strings.HasPrefix(option, "table="):
+				table = true
+			case option == "counters":
+				counters = true
+			case option == "presence":
+				presence = true
+			case option == "counter" || option == "type=counter":
+				for _, name := range f.Names {
+					counterFields = append(counterFields, name.Name)
+				}
+			}
+		}
+		collectionFields = append(collectionFields, v.parseCollectionFields(f, tag)...)
+	}
+
+	if table {
+		if v.TableStructs == nil {
+			v.TableStructs = make(map[string]bool)
+		}
+		v.TableStructs[v.name] = counters
+	}
+	if table && counters {
+		if v.CounterFields == nil {
+			v.CounterFields = make(map[string][]string)
+		}
+		v.CounterFields[v.name] = counterFields
+	}
+	if table && !counters && len(collectionFields) > 0 {
+		if v.CollectionFields == nil {
+			v.CollectionFields = make(map[string][]CollectionField)
+		}
+		v.CollectionFields[v.name] = collectionFields
+	}
+	if presence {
+		if v.PresenceStructs == nil {
+			v.PresenceStructs = make(map[string]bool)
+		}
+		v.PresenceStructs[v.name] = true
+	}
+}
+
+// parseCollectionFields returns collection fields declared by f with the given tag that are regular columns of
+// a table. Kind of the collection is taken from the CQL type in the tag the same way as the generator does, types
+// that are collections only through named types are not recognized.
+func (v *visitor) parseCollectionFields(f *ast.Field, tag string) []CollectionField {
+	var typ string
+	var named bool
+	for i, option := range gen.SplitTagOptions(reflect.StructTag(tag).Get("easycql")) {
+		switch {
+		case i == 0:
+			if option == "-" {
+				return nil
+			}
+			named = option != ""
+		case option == "raw", option == "pk", option == "ck", option == "writetime", option == "ttl",
+			option == "presence", option == "unknown", option == "inline", strings.HasPrefix(option, "inline="),
+			strings.HasPrefix(option, "codec="):
+			return nil
+		case strings.HasPrefix(option, "type="):
+			typ = strings.TrimSpace(strings.TrimPrefix(option, "type="))
+		case strings.Contains(option, "="):
+		case option != "required" && option != "omitempty" && option != "notnull" && option != "counters":
+			// Other options are names of CQL types.
+			typ = option
+		}
+	}
+	if strings.HasPrefix(typ, "frozen") {
+		return nil
+	}
+	if i := strings.Index(typ, "<"); i >= 0 {
+		typ = strings.TrimSpace(typ[:i])
+	}
+
+	var field CollectionField
+	switch t := f.Type.(type) {
+	case *ast.ArrayType:
+		if elem, ok := t.Elt.(*ast.Ident); ok && (elem.Name == "byte" || elem.Name == "uint8") {
+			return nil
+		}
+		field.List = typ == "" || typ == "list"
+		field.Set = typ == "" || typ == "set"
+		if !field.List && !field.Set {
+			return nil
+		}
+		field.Elem = v.typeString(t.Elt)
+	case *ast.MapType:
+		field.Key = v.typeString(t.Key)
+		field.Elem = v.typeString(t.Value)
+	default:
+		return nil
+	}
+
+	var fields []CollectionField
+	for _, name := range f.Names {
+		if name.Name == "_" || !name.IsExported() && !named {
+			continue
+		}
+		field.Name = name.Name
+		fields = append(fields, field)
+	}
+	return fields
+}
+
+// typeString returns the source of type expression e and records packages it refers to in Imports.
+func (v *visitor) typeString(e ast.Expr) string {
+	ast.Inspect(e, func(n ast.Node) bool {
+		sel, ok := n.(*ast.SelectorExpr)
+		if !ok {
+			return true
+		}
+		if pkg, ok := sel.X.(*ast.Ident); ok && v.imports[pkg.Name] != "" {
+			if v.Imports == nil {
+				v.Imports = make(map[string]string)
+			}
+			v.Imports[pkg.Name] = v.imports[pkg.Name]
+		}
+		return false
+	})
+	return types.ExprString(e)
+}
+
 func (p *Parser) Parse(fname string, isDir bool) error {
 	var err error
 	if p.PkgPath, err = getPkgPath(fname, isDir); err != nil {
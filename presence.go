@@ -0,0 +1,90 @@
+package easycql
+
+// FieldState is the state of a UDT field in the last decoded value.
+type FieldState uint8
+
+const (
+	// FieldAbsent means the field was missing from the UDT value, e.g. the value was stored before
+	// the field was added to the UDT.
+	FieldAbsent FieldState = iota
+	// FieldNull means the field was present with a null value.
+	FieldNull
+	// FieldSet means the field was present with a non-null value.
+	FieldSet
+)
+
+func (s FieldState) String() string {
+	switch s {
+	case FieldAbsent:
+		return "absent"
+	case FieldNull:
+		return "null"
+	case FieldSet:
+		return "set"
+	}
+	return "unknown"
+}
+
+// fieldsPerWord is the number of field states stored in a single word of FieldPresence, each takes 2 bits.
+const fieldsPerWord = 32
+
+// FieldPresence is a bitset recording FieldState of each field of a struct.
+// Generated decoders fill it when the struct has a field of this type tagged with presence.
+type FieldPresence struct {
+	bits []uint64
+	// first is one plus the index of the first field recorded by RecordUDT since the last Reset, zero if none.
+	first int
+}
+
+// Reset sets the state of all n fields to FieldAbsent.
+func (p *FieldPresence) Reset(n int) {
+	p.first = 0
+	words := (n + fieldsPerWord - 1) / fieldsPerWord
+	if cap(p.bits) < words {
+		p.bits = make([]uint64, words)
+		return
+	}
+	p.bits = p.bits[:words]
+	for i := range p.bits {
+		p.bits[i] = 0
+	}
+}
+
+// Record records the state of i-th field decoded from data.
+func (p *FieldPresence) Record(i int, data []byte) {
+	if data == nil {
+		p.Set(i, FieldNull)
+	} else {
+		p.Set(i, FieldSet)
+	}
+}
+
+// RecordUDT records the state of i-th of n fields decoded by UnmarshalUDT. gocql calls UnmarshalUDT once
+// for each element of the value in the order of the UDT elements, so decoding of a value starts when the first
+// recorded field is recorded again, and the states of all fields are reset then.
+func (p *FieldPresence) RecordUDT(i, n int, data []byte) {
+	if p.first == 0 || p.first == i+1 {
+		p.Reset(n)
+		p.first = i + 1
+	}
+	p.Record(i, data)
+}
+
+// Set sets the state of i-th field.
+func (p *FieldPresence) Set(i int, s FieldState) {
+	w := i / fieldsPerWord
+	if w >= len(p.bits) {
+		p.bits = append(p.bits, make([]uint64, w-len(p.bits)+1)...)
+	}
+	shift := uint(i%fieldsPerWord) * 2
+	p.bits[w] = p.bits[w]&^(3<<shift) | uint64(s)<<shift
+}
+
+// Get returns the state of i-th field.
+func (p FieldPresence) Get(i int) FieldState {
+	w := i / fieldsPerWord
+	if w >= len(p.bits) {
+		return FieldAbsent
+	}
+	return FieldState(p.bits[w] >> (uint(i%fieldsPerWord) * 2) & 3)
+}
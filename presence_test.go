@@ -0,0 +1,54 @@
+package easycql
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFieldPresence(t *testing.T) {
+	t.Parallel()
+
+	var p FieldPresence
+	require.Equal(t, FieldAbsent, p.Get(0))
+
+	p.Reset(40)
+	p.Record(0, []byte{})
+	p.Record(1, nil)
+	p.Set(33, FieldSet)
+	p.Set(70, FieldNull)
+
+	require.Equal(t, FieldSet, p.Get(0))
+	require.Equal(t, FieldNull, p.Get(1))
+	require.Equal(t, FieldAbsent, p.Get(2))
+	require.Equal(t, FieldSet, p.Get(33))
+	require.Equal(t, FieldNull, p.Get(70))
+	require.Equal(t, FieldAbsent, p.Get(100))
+
+	p.Set(0, FieldNull)
+	require.Equal(t, FieldNull, p.Get(0))
+
+	p.Reset(40)
+	for i := 0; i < 100; i++ {
+		require.Equal(t, FieldAbsent, p.Get(i))
+	}
+}
+
+func TestFieldPresenceRecordUDT(t *testing.T) {
+	t.Parallel()
+
+	var p FieldPresence
+	p.Record(2, nil)
+
+	// The first recorded field resets the states left by previous decoding.
+	p.RecordUDT(1, 3, []byte{})
+	p.RecordUDT(0, 3, nil)
+	require.Equal(t, FieldNull, p.Get(0))
+	require.Equal(t, FieldSet, p.Get(1))
+	require.Equal(t, FieldAbsent, p.Get(2))
+
+	// Recording the first field again starts the next value.
+	p.RecordUDT(1, 3, nil)
+	require.Equal(t, FieldAbsent, p.Get(0))
+	require.Equal(t, FieldNull, p.Get(1))
+}
@@ -0,0 +1,58 @@
+package easycql
+
+import (
+	"fmt"
+
+	"github.com/gocql/gocql"
+)
+
+// RowScanner is implemented by structs with ScanCQL method generated by -row_scanners flag.
+type RowScanner interface {
+	// ScanCQL decodes a row with the given columns and their serialized values.
+	ScanCQL(columns []gocql.ColumnInfo, values [][]byte) error
+}
+
+// rawValue captures the serialized value of a column when passed to gocql Scan.
+type rawValue []byte
+
+func (r *rawValue) UnmarshalCQL(_ gocql.TypeInfo, data []byte) error {
+	*r = data
+	return nil
+}
+
+// RowReader reads rows of query results from gocql.Scanner into RowScanner without reflection.
+// Tuple columns are not supported as gocql scans their elements into separate values.
+type RowReader struct {
+	columns []gocql.ColumnInfo
+	values  [][]byte
+	dest    []interface{}
+}
+
+// NewRowReader returns a RowReader of rows with the given columns, usually gocql.Iter.Columns().
+// It returns an error if any of the columns is a tuple.
+func NewRowReader(columns []gocql.ColumnInfo) (*RowReader, error) {
+	for _, c := range columns {
+		if c.TypeInfo != nil && c.TypeInfo.Type() == gocql.TypeTuple {
+			return nil, fmt.Errorf("column %s: tuple columns are not supported", c.Name)
+		}
+	}
+
+	r := &RowReader{
+		columns: columns,
+		values:  make([][]byte, len(columns)),
+		dest:    make([]interface{}, len(columns)),
+	}
+	for i := range r.values {
+		r.dest[i] = (*rawValue)(&r.values[i])
+	}
+	return r, nil
+}
+
+// Scan scans the current row of scanner into out.
+// The serialized values are only valid until the next call of scanner.Next, ScanCQL decodes them before Scan returns.
+func (r *RowReader) Scan(scanner gocql.Scanner, out RowScanner) error {
+	if err := scanner.Scan(r.dest...); err != nil {
+		return err
+	}
+	return out.ScanCQL(r.columns, r.values)
+}
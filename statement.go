@@ -0,0 +1,51 @@
+package easycql
+
+import (
+	"time"
+
+	"github.com/gocql/gocql"
+)
+
+// Using specifies the optional USING clause of generated INSERT and UPDATE statements.
+type Using struct {
+	// TTL of the written values, rounded up to seconds so that a sub-second TTL still expires the values.
+	// Zero TTL omits the TTL clause.
+	TTL time.Duration
+	// Timestamp of the write in microseconds since Unix epoch. Zero timestamp omits the TIMESTAMP clause.
+	Timestamp int64
+}
+
+// Clause returns the USING clause with bind markers, prefixed by a space. It returns empty string when
+// neither TTL nor Timestamp is set.
+func (u Using) Clause() string {
+	switch {
+	case u.TTL != 0 && u.Timestamp != 0:
+		return " USING TTL ? AND TIMESTAMP ?"
+	case u.TTL != 0:
+		return " USING TTL ?"
+	case u.Timestamp != 0:
+		return " USING TIMESTAMP ?"
+	}
+	return ""
+}
+
+// Values returns the values bound to the markers of Clause.
+func (u Using) Values() []interface{} {
+	var values []interface{}
+	if u.TTL != 0 {
+		values = append(values, int((u.TTL+time.Second-1)/time.Second))
+	}
+	if u.Timestamp != 0 {
+		values = append(values, u.Timestamp)
+	}
+	return values
+}
+
+// MarshalerFunc is an adapter to use a function as gocql.Marshaler. Generated bind values use it to encode
+// fields with the same code as the generated marshalers.
+type MarshalerFunc func(info gocql.TypeInfo) ([]byte, error)
+
+// MarshalCQL implements gocql.Marshaler interface.
+func (f MarshalerFunc) MarshalCQL(info gocql.TypeInfo) ([]byte, error) {
+	return f(info)
+}
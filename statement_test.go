@@ -0,0 +1,52 @@
+package easycql
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestUsing(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		using          Using
+		expectedClause string
+		expectedValues []interface{}
+	}{
+		{
+			using: Using{},
+		},
+		{
+			using:          Using{TTL: time.Hour},
+			expectedClause: " USING TTL ?",
+			expectedValues: []interface{}{3600},
+		},
+		{
+			using:          Using{Timestamp: 1234},
+			expectedClause: " USING TIMESTAMP ?",
+			expectedValues: []interface{}{int64(1234)},
+		},
+		{
+			using:          Using{TTL: 90 * time.Second, Timestamp: 1234},
+			expectedClause: " USING TTL ? AND TIMESTAMP ?",
+			expectedValues: []interface{}{90, int64(1234)},
+		},
+		{
+			using:          Using{TTL: 500 * time.Millisecond},
+			expectedClause: " USING TTL ?",
+			expectedValues: []interface{}{1},
+		},
+		{
+			using:          Using{TTL: 90*time.Second + time.Millisecond},
+			expectedClause: " USING TTL ?",
+			expectedValues: []interface{}{91},
+		},
+	}
+
+	for _, test := range tests {
+		require.Equal(t, test.expectedClause, test.using.Clause())
+		require.Equal(t, test.expectedValues, test.using.Values())
+	}
+}
@@ -0,0 +1,16 @@
+package tests
+
+// easycql:cql
+type CollectionInts []int
+
+// easycql:cql
+type CollectionArray [2]int16
+
+// easycql:cql
+type CollectionMap map[string]int
+
+type TypedCollectionStruct struct {
+	Tags   []string           `easycql:"tags,type=set<ascii>"`
+	Scores map[string][]int16 `easycql:"scores,type=frozen<map<text,list<smallint>>>"`
+	Matrix [][2]int32         `easycql:"matrix,type=list<frozen<list<int>>>"`
+}
@@ -0,0 +1,220 @@
+package tests
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/gocql/gocql"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kiwicom/easycql/marshal"
+)
+
+var collectionTests = []struct {
+	Name     string
+	TypeInfo gocql.TypeInfo
+	Data     []byte
+	Value    interface{}
+}{
+	{
+		Name: "list v2",
+		TypeInfo: gocql.CollectionType{
+			NativeType: gocql.NewNativeType(2, gocql.TypeList, ""),
+			Elem:       gocql.NewNativeType(2, gocql.TypeInt, ""),
+		},
+		Data:  []byte("\x00\x02\x00\x04\x00\x00\x00\x01\x00\x04\x00\x00\x00\x02"),
+		Value: CollectionInts{1, 2},
+	},
+	{
+		Name: "list v3",
+		TypeInfo: gocql.CollectionType{
+			NativeType: gocql.NewNativeType(3, gocql.TypeList, ""),
+			Elem:       gocql.NewNativeType(3, gocql.TypeInt, ""),
+		},
+		Data:  []byte("\x00\x00\x00\x02\x00\x00\x00\x04\x00\x00\x00\x01\x00\x00\x00\x04\x00\x00\x00\x02"),
+		Value: CollectionInts{1, 2},
+	},
+	{
+		Name: "empty list v3",
+		TypeInfo: gocql.CollectionType{
+			NativeType: gocql.NewNativeType(3, gocql.TypeList, ""),
+			Elem:       gocql.NewNativeType(3, gocql.TypeInt, ""),
+		},
+		Data:  []byte("\x00\x00\x00\x00"),
+		Value: CollectionInts{},
+	},
+	{
+		Name: "null list",
+		TypeInfo: gocql.CollectionType{
+			NativeType: gocql.NewNativeType(3, gocql.TypeList, ""),
+			Elem:       gocql.NewNativeType(3, gocql.TypeInt, ""),
+		},
+		Data:  nil,
+		Value: CollectionInts(nil),
+	},
+	{
+		Name: "set v2",
+		TypeInfo: gocql.CollectionType{
+			NativeType: gocql.NewNativeType(2, gocql.TypeSet, ""),
+			Elem:       gocql.NewNativeType(2, gocql.TypeSmallInt, ""),
+		},
+		Data:  []byte("\x00\x02\x00\x02\x00\x01\x00\x02\x00\x02"),
+		Value: CollectionArray{1, 2},
+	},
+	{
+		Name: "set v4",
+		TypeInfo: gocql.CollectionType{
+			NativeType: gocql.NewNativeType(4, gocql.TypeSet, ""),
+			Elem:       gocql.NewNativeType(4, gocql.TypeSmallInt, ""),
+		},
+		Data:  []byte("\x00\x00\x00\x02\x00\x00\x00\x02\x00\x01\x00\x00\x00\x02\x00\x02"),
+		Value: CollectionArray{1, 2},
+	},
+	{
+		Name: "map v2",
+		TypeInfo: gocql.CollectionType{
+			NativeType: gocql.NewNativeType(2, gocql.TypeMap, ""),
+			Key:        gocql.NewNativeType(2, gocql.TypeVarchar, ""),
+			Elem:       gocql.NewNativeType(2, gocql.TypeInt, ""),
+		},
+		Data:  []byte("\x00\x01\x00\x01a\x00\x04\x00\x00\x00\x01"),
+		Value: CollectionMap{"a": 1},
+	},
+	{
+		Name: "map v3",
+		TypeInfo: gocql.CollectionType{
+			NativeType: gocql.NewNativeType(3, gocql.TypeMap, ""),
+			Key:        gocql.NewNativeType(3, gocql.TypeVarchar, ""),
+			Elem:       gocql.NewNativeType(3, gocql.TypeInt, ""),
+		},
+		Data:  []byte("\x00\x00\x00\x01\x00\x00\x00\x01a\x00\x00\x00\x04\x00\x00\x00\x01"),
+		Value: CollectionMap{"a": 1},
+	},
+}
+
+func TestUnmarshalCollection(t *testing.T) {
+	t.Parallel()
+	for _, test := range collectionTests {
+		test := test
+		t.Run(test.Name, func(t *testing.T) {
+			t.Parallel()
+			value := reflect.New(reflect.TypeOf(test.Value))
+			err := gocql.Unmarshal(test.TypeInfo, test.Data, value.Interface())
+			require.NoError(t, err)
+			require.Equal(t, test.Value, value.Elem().Interface())
+		})
+	}
+}
+
+func TestMarshalCollection(t *testing.T) {
+	t.Parallel()
+	for _, test := range collectionTests {
+		test := test
+		t.Run(test.Name, func(t *testing.T) {
+			t.Parallel()
+			data, err := gocql.Marshal(test.TypeInfo, test.Value)
+			require.NoError(t, err)
+			require.Equal(t, test.Data, data)
+		})
+	}
+}
+
+func TestUnmarshalArrayWrongSize(t *testing.T) {
+	t.Parallel()
+	typeInfo := gocql.CollectionType{
+		NativeType: gocql.NewNativeType(3, gocql.TypeList, ""),
+		Elem:       gocql.NewNativeType(3, gocql.TypeSmallInt, ""),
+	}
+	var value CollectionArray
+	err := gocql.Unmarshal(typeInfo, []byte("\x00\x00\x00\x01\x00\x00\x00\x02\x00\x01"), &value)
+	require.Error(t, err)
+}
+
+func TestUDTUnavailable(t *testing.T) {
+	t.Parallel()
+	typeInfo := gocql.UDTTypeInfo{
+		NativeType: gocql.NewNativeType(2, gocql.TypeUDT, ""),
+		KeySpace:   "myKeyspace",
+		Name:       "SingleInt16UDT",
+		Elements: []gocql.UDTField{
+			{
+				Name: "Int16",
+				Type: gocql.NewNativeType(2, gocql.TypeSmallInt, ""),
+			},
+		},
+	}
+
+	t.Run("marshal", func(t *testing.T) {
+		t.Parallel()
+		_, err := gocql.Marshal(typeInfo, SingleInt16{Int16: 42})
+		require.Equal(t, marshal.ErrorUDTUnavailable, err)
+	})
+
+	t.Run("unmarshal", func(t *testing.T) {
+		t.Parallel()
+		var value SingleInt16
+		err := gocql.Unmarshal(typeInfo, []byte("\x00\x00\x00\x02\x00\x2a"), &value)
+		require.Equal(t, marshal.ErrorUDTUnavailable, err)
+	})
+}
+
+func TestTypedCollectionField(t *testing.T) {
+	t.Parallel()
+
+	typeInfo := gocql.UDTTypeInfo{
+		NativeType: gocql.NewNativeType(3, gocql.TypeUDT, ""),
+		KeySpace:   "myKeyspace",
+		Name:       "TypedCollectionUDT",
+		Elements: []gocql.UDTField{
+			{
+				Name: "tags",
+				Type: gocql.CollectionType{
+					NativeType: gocql.NewNativeType(3, gocql.TypeSet, ""),
+					Elem:       gocql.NewNativeType(3, gocql.TypeAscii, ""),
+				},
+			},
+			{
+				Name: "scores",
+				Type: gocql.CollectionType{
+					NativeType: gocql.NewNativeType(3, gocql.TypeMap, ""),
+					Key:        gocql.NewNativeType(3, gocql.TypeText, ""),
+					Elem: gocql.CollectionType{
+						NativeType: gocql.NewNativeType(3, gocql.TypeList, ""),
+						Elem:       gocql.NewNativeType(3, gocql.TypeSmallInt, ""),
+					},
+				},
+			},
+			{
+				Name: "matrix",
+				Type: gocql.CollectionType{
+					NativeType: gocql.NewNativeType(3, gocql.TypeList, ""),
+					Elem: gocql.CollectionType{
+						NativeType: gocql.NewNativeType(3, gocql.TypeList, ""),
+						Elem:       gocql.NewNativeType(3, gocql.TypeInt, ""),
+					},
+				},
+			},
+		},
+	}
+
+	var expectedData []byte
+	expectedData = marshal.AppendBytes(expectedData, []byte("\x00\x00\x00\x01\x00\x00\x00\x01a"))
+	expectedData = marshal.AppendBytes(expectedData, []byte("\x00\x00\x00\x01\x00\x00\x00\x01x"+
+		"\x00\x00\x00\x0a\x00\x00\x00\x01\x00\x00\x00\x02\x00\x07"))
+	expectedData = marshal.AppendBytes(expectedData, []byte("\x00\x00\x00\x01\x00\x00\x00\x14"+
+		"\x00\x00\x00\x02\x00\x00\x00\x04\x00\x00\x00\x01\x00\x00\x00\x04\x00\x00\x00\x02"))
+	value := TypedCollectionStruct{
+		Tags:   []string{"a"},
+		Scores: map[string][]int16{"x": {7}},
+		Matrix: [][2]int32{{1, 2}},
+	}
+
+	data, err := gocql.Marshal(typeInfo, value)
+	require.NoError(t, err)
+	require.Equal(t, expectedData, data)
+
+	var unmarshaled TypedCollectionStruct
+	err = gocql.Unmarshal(typeInfo, expectedData, &unmarshaled)
+	require.NoError(t, err)
+	require.Equal(t, value, unmarshaled)
+}
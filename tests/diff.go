@@ -0,0 +1,14 @@
+package tests
+
+import "time"
+
+type DiffAddress struct {
+	Street   string    `easycql:"street"`
+	City     string    `easycql:"city"`
+	Zip      *int      `easycql:"zip"`
+	Lines    []string  `easycql:"lines"`
+	Note     string    `easycql:"note,omitempty"`
+	Location []byte    `easycql:"location,raw"`
+	Verified time.Time `easycql:"verified"`
+	Internal string    `easycql:"-"`
+}
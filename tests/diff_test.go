@@ -0,0 +1,54 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gocql/gocql"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiffCQL(t *testing.T) {
+	t.Parallel()
+
+	zip := 12000
+	verified := time.Date(2021, 1, 2, 3, 4, 5, 0, time.UTC)
+	old := DiffAddress{Street: "Main", City: "Brno", Lines: []string{"a"}, Location: []byte{1},
+		Verified: verified, Internal: "x"}
+	value := DiffAddress{Street: "Main", City: "Prague", Zip: &zip, Lines: []string{"a"}, Location: []byte{2},
+		Verified: verified.In(time.FixedZone("CET", 3600)), Internal: "y"}
+
+	assignments, values := value.DiffCQL("address", &old)
+	require.Equal(t, []string{"address.city = ?", "address.zip = ?", "address.location = ?"}, assignments)
+	require.Len(t, values, 3)
+
+	// Values are encoded with the CQL type of the UDT field.
+	data, err := gocql.Marshal(gocql.NewNativeType(4, gocql.TypeVarchar, ""), values[0])
+	require.NoError(t, err)
+	require.Equal(t, []byte("Prague"), data)
+	data, err = gocql.Marshal(gocql.NewNativeType(4, gocql.TypeBigInt, ""), values[1])
+	require.NoError(t, err)
+	require.Equal(t, []byte{0, 0, 0, 0, 0, 0, 0x2e, 0xe0}, data)
+	data, err = gocql.Marshal(gocql.NewNativeType(4, gocql.TypeTinyInt, ""), values[2])
+	require.NoError(t, err)
+	require.Equal(t, []byte{2}, data)
+
+	// Pointers are compared by the values they point to.
+	sameZip := zip
+	same := value
+	same.Zip = &sameZip
+	same.Lines = []string{"a"}
+	assignments, values = value.DiffCQL("address", &same)
+	require.Empty(t, assignments)
+	require.Empty(t, values)
+
+	assignments, values = value.DiffCQL(`"Address"`, nil)
+	require.Equal(t, []string{`"Address".street = ?`, `"Address".city = ?`, `"Address".zip = ?`,
+		`"Address".lines = ?`, `"Address".note = ?`, `"Address".location = ?`, `"Address".verified = ?`}, assignments)
+	require.Len(t, values, 7)
+
+	// note has omitempty, so the empty value is bound as null.
+	data, err = gocql.Marshal(gocql.NewNativeType(4, gocql.TypeVarchar, ""), values[4])
+	require.NoError(t, err)
+	require.Nil(t, data)
+}
@@ -0,0 +1,12 @@
+// Package external declares types embedded by structs of the tests package.
+package external
+
+type Base struct {
+	ID     string `easycql:"id"`
+	secret string `easycql:"secret"`
+	note   string `cql:"note"`
+}
+
+func NewBase(id, secret, note string) Base {
+	return Base{ID: id, secret: secret, note: note}
+}
@@ -0,0 +1,7 @@
+package tests
+
+type FieldNamerStruct struct {
+	ColID    int
+	ColName  string
+	Explicit string `easycql:"explicit_name"`
+}
@@ -0,0 +1,39 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/gocql/gocql"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kiwicom/easycql/marshal"
+)
+
+func TestCustomFieldNamer(t *testing.T) {
+	t.Parallel()
+
+	typeInfo := gocql.UDTTypeInfo{
+		NativeType: gocql.NewNativeType(3, gocql.TypeUDT, ""),
+		KeySpace:   "myKeyspace",
+		Name:       "FieldNamerUDT",
+		Elements: []gocql.UDTField{
+			{Name: "id", Type: gocql.NewNativeType(3, gocql.TypeInt, "")},
+			{Name: "name", Type: gocql.NewNativeType(3, gocql.TypeVarchar, "")},
+			{Name: "explicit_name", Type: gocql.NewNativeType(3, gocql.TypeVarchar, "")},
+		},
+	}
+	var expectedData []byte
+	expectedData = marshal.AppendBytes(expectedData, []byte{0, 0, 0, 1})
+	expectedData = marshal.AppendBytes(expectedData, []byte("John"))
+	expectedData = marshal.AppendBytes(expectedData, []byte("hello"))
+	value := FieldNamerStruct{ColID: 1, ColName: "John", Explicit: "hello"}
+
+	data, err := gocql.Marshal(typeInfo, value)
+	require.NoError(t, err)
+	require.Equal(t, expectedData, data)
+
+	var unmarshaled FieldNamerStruct
+	err = gocql.Unmarshal(typeInfo, expectedData, &unmarshaled)
+	require.NoError(t, err)
+	require.Equal(t, value, unmarshaled)
+}
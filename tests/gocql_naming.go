@@ -0,0 +1,10 @@
+package tests
+
+// GocqlNamingStruct is generated with the default naming, which matches gocql's reflection based mapping.
+type GocqlNamingStruct struct {
+	ID        int
+	FirstName string `cql:"first_name"`
+	Age       int
+	Name      string `cql:"name"`
+	NameUpper string `cql:"NAME"`
+}
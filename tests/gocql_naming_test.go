@@ -0,0 +1,57 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/gocql/gocql"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kiwicom/easycql/marshal"
+)
+
+// reflectGocqlNamingStruct has the fields of GocqlNamingStruct without the generated methods, so gocql
+// (un)marshals it using reflection.
+type reflectGocqlNamingStruct GocqlNamingStruct
+
+func TestGocqlNaming(t *testing.T) {
+	t.Parallel()
+
+	// Elements differing from the mapped names only in case don't map to any field.
+	typeInfo := gocql.UDTTypeInfo{
+		NativeType: gocql.NewNativeType(3, gocql.TypeUDT, ""),
+		KeySpace:   "myKeyspace",
+		Name:       "GocqlNamingUDT",
+		Elements: []gocql.UDTField{
+			{Name: "ID", Type: gocql.NewNativeType(3, gocql.TypeInt, "")},
+			{Name: "first_name", Type: gocql.NewNativeType(3, gocql.TypeVarchar, "")},
+			{Name: "age", Type: gocql.NewNativeType(3, gocql.TypeInt, "")},
+			{Name: "First_Name", Type: gocql.NewNativeType(3, gocql.TypeVarchar, "")},
+			{Name: "name", Type: gocql.NewNativeType(3, gocql.TypeVarchar, "")},
+			{Name: "NAME", Type: gocql.NewNativeType(3, gocql.TypeVarchar, "")},
+		},
+	}
+	value := GocqlNamingStruct{ID: 1, FirstName: "John", Age: 42, Name: "lower", NameUpper: "upper"}
+
+	data, err := gocql.Marshal(typeInfo, value)
+	require.NoError(t, err)
+	expectedData, err := gocql.Marshal(typeInfo, reflectGocqlNamingStruct(value))
+	require.NoError(t, err)
+	require.Equal(t, expectedData, data)
+
+	var input []byte
+	input = marshal.AppendBytes(input, []byte{0, 0, 0, 1})
+	input = marshal.AppendBytes(input, []byte("John"))
+	input = marshal.AppendBytes(input, []byte{0, 0, 0, 42})
+	input = marshal.AppendBytes(input, []byte("Jane"))
+	input = marshal.AppendBytes(input, []byte("lower"))
+	input = marshal.AppendBytes(input, []byte("upper"))
+
+	var unmarshaled GocqlNamingStruct
+	err = gocql.Unmarshal(typeInfo, input, &unmarshaled)
+	require.NoError(t, err)
+	var expected reflectGocqlNamingStruct
+	err = gocql.Unmarshal(typeInfo, input, &expected)
+	require.NoError(t, err)
+	require.Equal(t, GocqlNamingStruct(expected), unmarshaled)
+	require.Equal(t, GocqlNamingStruct{ID: 1, FirstName: "John", Name: "lower", NameUpper: "upper"}, unmarshaled)
+}
@@ -0,0 +1,32 @@
+package tests
+
+import (
+	"errors"
+	"strings"
+)
+
+type HookStruct struct {
+	Email  string `easycql:"email"`
+	Domain string `easycql:"domain"`
+}
+
+var errInvalidEmail = errors.New("invalid email")
+
+// BeforeMarshalCQL derives the domain from the email.
+func (h *HookStruct) BeforeMarshalCQL() error {
+	i := strings.IndexByte(h.Email, '@')
+	if i < 0 {
+		return errInvalidEmail
+	}
+	h.Domain = h.Email[i+1:]
+	return nil
+}
+
+// AfterUnmarshalCQL normalizes the email.
+func (h *HookStruct) AfterUnmarshalCQL() error {
+	if !strings.Contains(h.Email, "@") {
+		return errInvalidEmail
+	}
+	h.Email = strings.ToLower(h.Email)
+	return nil
+}
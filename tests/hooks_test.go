@@ -0,0 +1,52 @@
+package tests
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/gocql/gocql"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kiwicom/easycql/marshal"
+)
+
+func TestHooks(t *testing.T) {
+	t.Parallel()
+
+	typeInfo := gocql.UDTTypeInfo{
+		NativeType: gocql.NewNativeType(3, gocql.TypeUDT, ""),
+		KeySpace:   "myKeyspace",
+		Name:       "HookUDT",
+		Elements: []gocql.UDTField{
+			{Name: "email", Type: gocql.NewNativeType(3, gocql.TypeVarchar, "")},
+			{Name: "domain", Type: gocql.NewNativeType(3, gocql.TypeVarchar, "")},
+		},
+	}
+
+	var expectedData []byte
+	expectedData = marshal.AppendBytes(expectedData, []byte("John@Example.com"))
+	expectedData = marshal.AppendBytes(expectedData, []byte("Example.com"))
+
+	value := HookStruct{Email: "John@Example.com"}
+	data, err := gocql.Marshal(typeInfo, value)
+	require.NoError(t, err)
+	require.Equal(t, expectedData, data)
+	// MarshalCQL has a value receiver, so the hook modifies a copy.
+	require.Equal(t, "", value.Domain)
+
+	var unmarshaled HookStruct
+	err = gocql.Unmarshal(typeInfo, expectedData, &unmarshaled)
+	require.NoError(t, err)
+	require.Equal(t, HookStruct{Email: "john@example.com", Domain: "Example.com"}, unmarshaled)
+
+	_, err = gocql.Marshal(typeInfo, HookStruct{Email: "john"})
+	require.EqualError(t, err, "HookStruct.BeforeMarshalCQL: invalid email")
+	require.True(t, errors.Is(err, errInvalidEmail))
+
+	var invalidData []byte
+	invalidData = marshal.AppendBytes(invalidData, []byte("john"))
+	invalidData = marshal.AppendBytes(invalidData, nil)
+	err = gocql.Unmarshal(typeInfo, invalidData, &unmarshaled)
+	require.EqualError(t, err, "HookStruct.AfterUnmarshalCQL: invalid email")
+	require.True(t, errors.Is(err, errInvalidEmail))
+}
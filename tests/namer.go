@@ -0,0 +1,16 @@
+package tests
+
+import (
+	"reflect"
+	"strings"
+)
+
+// ColumnFieldNamer is a custom gen.FieldNamer used with -field_namer flag. It strips Col prefix of field names
+// and lowercases them.
+type ColumnFieldNamer struct{}
+
+// GetCQLFieldName returns the name of the field in CQL based on Go struct field.
+//nolint:gocritic // parameter f is huge
+func (ColumnFieldNamer) GetCQLFieldName(t reflect.Type, f reflect.StructField) string {
+	return strings.ToLower(strings.TrimPrefix(f.Name, "Col"))
+}
@@ -0,0 +1,23 @@
+package tests
+
+// LargePointerStruct has MarshalCQL with a pointer receiver generated by -pointer_marshalers flag.
+type LargePointerStruct struct {
+	Name    string     `easycql:"name"`
+	Count   int        `easycql:"count"`
+	Padding [2048]byte `easycql:"-"`
+}
+
+// LargeValueStruct has the same fields as LargePointerStruct, but overrides the flag and has MarshalCQL with
+// a value receiver.
+type LargeValueStruct struct {
+	_       struct{}   `easycql:",receiver=value"`
+	Name    string     `easycql:"name"`
+	Count   int        `easycql:"count"`
+	Padding [2048]byte `easycql:"-"`
+}
+
+// PointerMarshalerHolder has a field of a type with pointer receiver MarshalCQL.
+type PointerMarshalerHolder struct {
+	_     struct{}           `easycql:",receiver=value"`
+	Large LargePointerStruct `easycql:"large"`
+}
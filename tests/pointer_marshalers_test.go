@@ -0,0 +1,126 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/gocql/gocql"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kiwicom/easycql/marshal"
+)
+
+var largeTypeInfo = gocql.UDTTypeInfo{
+	NativeType: gocql.NewNativeType(3, gocql.TypeUDT, ""),
+	KeySpace:   "myKeyspace",
+	Name:       "LargeUDT",
+	Elements: []gocql.UDTField{
+		{Name: "name", Type: gocql.NewNativeType(3, gocql.TypeVarchar, "")},
+		{Name: "count", Type: gocql.NewNativeType(3, gocql.TypeInt, "")},
+	},
+}
+
+func TestPointerMarshaler(t *testing.T) {
+	t.Parallel()
+
+	var expectedData []byte
+	expectedData = marshal.AppendBytes(expectedData, []byte("hello"))
+	expectedData = marshal.AppendBytes(expectedData, []byte{0, 0, 0, 42})
+
+	value := LargePointerStruct{Name: "hello", Count: 42}
+	var _ gocql.Marshaler = &value
+
+	data, err := gocql.Marshal(largeTypeInfo, &value)
+	require.NoError(t, err)
+	require.Equal(t, expectedData, data)
+
+	data, err = gocql.Marshal(largeTypeInfo, (*LargePointerStruct)(nil))
+	require.NoError(t, err)
+	require.Nil(t, data)
+
+	var unmarshaled LargePointerStruct
+	err = gocql.Unmarshal(largeTypeInfo, expectedData, &unmarshaled)
+	require.NoError(t, err)
+	require.Equal(t, value, unmarshaled)
+}
+
+func TestPointerMarshalerField(t *testing.T) {
+	t.Parallel()
+
+	typeInfo := gocql.UDTTypeInfo{
+		NativeType: gocql.NewNativeType(3, gocql.TypeUDT, ""),
+		KeySpace:   "myKeyspace",
+		Name:       "HolderUDT",
+		Elements: []gocql.UDTField{
+			{Name: "large", Type: largeTypeInfo},
+		},
+	}
+
+	var largeData []byte
+	largeData = marshal.AppendBytes(largeData, []byte("hello"))
+	largeData = marshal.AppendBytes(largeData, []byte{0, 0, 0, 42})
+	expectedData := marshal.AppendBytes(nil, largeData)
+
+	// Generated encoder of the holder passes the field by pointer, so the generated MarshalCQL is used.
+	value := PointerMarshalerHolder{Large: LargePointerStruct{Name: "hello", Count: 42}}
+	data, err := gocql.Marshal(typeInfo, value)
+	require.NoError(t, err)
+	require.Equal(t, expectedData, data)
+}
+
+func TestPointerMarshalerAllocs(t *testing.T) {
+	holderTypeInfo := gocql.UDTTypeInfo{
+		NativeType: gocql.NewNativeType(3, gocql.TypeUDT, ""),
+		KeySpace:   "myKeyspace",
+		Name:       "HolderUDT",
+		Elements: []gocql.UDTField{
+			{Name: "large", Type: largeTypeInfo},
+		},
+	}
+	value := LargePointerStruct{Name: "hello", Count: 42}
+	holder := PointerMarshalerHolder{Large: value}
+
+	// Neither the struct nor the holder passing its field to gocql are copied to the heap.
+	result := testing.Benchmark(func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			_, _ = gocql.Marshal(largeTypeInfo, &value)
+		}
+	})
+	require.Less(t, result.AllocedBytesPerOp(), int64(len(value.Padding)))
+
+	result = testing.Benchmark(func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			_, _ = gocql.Marshal(holderTypeInfo, &holder)
+		}
+	})
+	require.Less(t, result.AllocedBytesPerOp(), int64(len(value.Padding)))
+}
+
+func BenchmarkMarshalLargeValueReceiver(b *testing.B) {
+	value := LargeValueStruct{Name: "hello", Count: 42}
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		data, err := gocql.Marshal(largeTypeInfo, value)
+		if err != nil {
+			b.Fatal(err)
+		}
+		benchmarkMarshalOut = data
+	}
+}
+
+func BenchmarkMarshalLargePointerReceiver(b *testing.B) {
+	value := LargePointerStruct{Name: "hello", Count: 42}
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		data, err := gocql.Marshal(largeTypeInfo, &value)
+		if err != nil {
+			b.Fatal(err)
+		}
+		benchmarkMarshalOut = data
+	}
+}
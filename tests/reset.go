@@ -0,0 +1,13 @@
+package tests
+
+type ResetStruct struct {
+	Name  string `easycql:"name"`
+	Count int    `easycql:"count"`
+}
+
+type MergeStruct struct {
+	_        struct{} `easycql:",decode=merge"`
+	Name     string   `easycql:"name"`
+	Count    int      `easycql:"count"`
+	Currency string   `easycql:"currency,default=EUR"`
+}
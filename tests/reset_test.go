@@ -0,0 +1,113 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/gocql/gocql"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kiwicom/easycql/marshal"
+)
+
+var resetTypeInfo = gocql.UDTTypeInfo{
+	NativeType: gocql.NewNativeType(3, gocql.TypeUDT, ""),
+	KeySpace:   "myKeyspace",
+	Name:       "ResetUDT",
+	Elements: []gocql.UDTField{
+		{Name: "name", Type: gocql.NewNativeType(3, gocql.TypeVarchar, "")},
+		{Name: "count", Type: gocql.NewNativeType(3, gocql.TypeInt, "")},
+	},
+}
+
+func TestDecodeReset(t *testing.T) {
+	t.Parallel()
+
+	// count is missing from the short data.
+	data := marshal.AppendBytes(nil, []byte("hello"))
+
+	value := ResetStruct{Name: "stale", Count: 42}
+	err := gocql.Unmarshal(resetTypeInfo, data, &value)
+	require.NoError(t, err)
+	require.Equal(t, ResetStruct{Name: "hello"}, value)
+
+	value = ResetStruct{Name: "stale", Count: 42}
+	err = gocql.Unmarshal(resetTypeInfo, nil, &value)
+	require.NoError(t, err)
+	require.Equal(t, ResetStruct{}, value)
+}
+
+func TestDecodeMerge(t *testing.T) {
+	t.Parallel()
+
+	data := marshal.AppendBytes(nil, []byte("hello"))
+
+	value := MergeStruct{Name: "stale", Count: 42}
+	err := gocql.Unmarshal(resetTypeInfo, data, &value)
+	require.NoError(t, err)
+	require.Equal(t, MergeStruct{Name: "hello", Count: 42}, value)
+}
+
+func TestDecodeMergeDefault(t *testing.T) {
+	t.Parallel()
+
+	typeInfo := gocql.UDTTypeInfo{
+		NativeType: gocql.NewNativeType(3, gocql.TypeUDT, ""),
+		KeySpace:   "myKeyspace",
+		Name:       "MergeUDT",
+		Elements: []gocql.UDTField{
+			{Name: "currency", Type: gocql.NewNativeType(3, gocql.TypeVarchar, "")},
+			{Name: "name", Type: gocql.NewNativeType(3, gocql.TypeVarchar, "")},
+		},
+	}
+
+	// An absent field keeps its previous value.
+	value := MergeStruct{Name: "stale", Count: 42, Currency: "USD"}
+	err := gocql.Unmarshal(resetTypeInfo, marshal.AppendBytes(nil, []byte("hello")), &value)
+	require.NoError(t, err)
+	require.Equal(t, MergeStruct{Name: "hello", Count: 42, Currency: "USD"}, value)
+
+	// A null value gets the default too.
+	value = MergeStruct{Name: "stale", Currency: "USD"}
+	data := marshal.AppendBytes(nil, nil)
+	data = marshal.AppendBytes(data, []byte("hello"))
+	err = gocql.Unmarshal(typeInfo, data, &value)
+	require.NoError(t, err)
+	require.Equal(t, MergeStruct{Name: "hello", Currency: "EUR"}, value)
+
+	// A present value is not overwritten by the default.
+	value = MergeStruct{Name: "stale", Currency: "USD"}
+	data = marshal.AppendBytes(nil, []byte("CZK"))
+	data = marshal.AppendBytes(data, []byte("hello"))
+	err = gocql.Unmarshal(typeInfo, data, &value)
+	require.NoError(t, err)
+	require.Equal(t, MergeStruct{Name: "hello", Currency: "CZK"}, value)
+}
+
+func TestDecodeShortDataRequired(t *testing.T) {
+	t.Parallel()
+
+	typeInfo := gocql.UDTTypeInfo{
+		NativeType: gocql.NewNativeType(3, gocql.TypeUDT, ""),
+		KeySpace:   "myKeyspace",
+		Name:       "RenamedFieldUDT",
+		Elements: []gocql.UDTField{
+			{Name: "value", Type: gocql.NewNativeType(3, gocql.TypeInt, "")},
+			{Name: "new_name", Type: gocql.NewNativeType(3, gocql.TypeVarchar, "")},
+		},
+	}
+	data := marshal.AppendBytes(nil, []byte{0, 0, 0, 42})
+
+	// Short values are accepted without checking required fields.
+	var value RenamedFieldStruct
+	err := gocql.Unmarshal(typeInfo, data, &value)
+	require.NoError(t, err)
+	require.Equal(t, RenamedFieldStruct{Value: 42}, value)
+
+	data = marshal.AppendBytes(data, nil)
+	err = gocql.Unmarshal(typeInfo, data, &value)
+	require.NoError(t, err)
+
+	typeInfo.Elements = typeInfo.Elements[:1]
+	err = gocql.Unmarshal(typeInfo, data[:8], &value)
+	require.EqualError(t, err, "key 'new_name' is required")
+}
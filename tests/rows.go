@@ -0,0 +1,42 @@
+package tests
+
+import "time"
+
+type RowLocation struct {
+	Lat float64 `easycql:"lat"`
+	Lon float64 `easycql:"lon"`
+}
+
+type RowStruct struct {
+	ID       string            `easycql:"id,required"`
+	Name     string            `easycql:"name,notnull"`
+	Score    int64             `easycql:"score,default=10"`
+	Tags     []string          `easycql:"tags"`
+	Location RowLocation       `easycql:"location"`
+	Extra    map[string][]byte `easycql:",unknown"`
+}
+
+type RowMetaStruct struct {
+	ID           string        `easycql:"id"`
+	Price        int64         `easycql:"price"`
+	PriceWritten time.Time     `easycql:"price,writetime"`
+	PriceTTL     time.Duration `easycql:"price,ttl"`
+}
+
+type RowMetaIntStruct struct {
+	PriceWritten int64 `easycql:"price,writetime"`
+	PriceTTL     int   `easycql:"price,ttl"`
+}
+
+type RowMetaEmbedded struct {
+	PriceWritten time.Time `easycql:"price,writetime"`
+}
+
+type RowMetaInline struct {
+	AmountTTL time.Duration `easycql:"amount,ttl"`
+}
+
+type RowEmbeddedMetaStruct struct {
+	RowMetaEmbedded
+	Discount RowMetaInline `easycql:",inline=discount_"`
+}
@@ -0,0 +1,197 @@
+package tests
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gocql/gocql"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kiwicom/easycql"
+	"github.com/kiwicom/easycql/marshal"
+)
+
+var rowColumns = []gocql.ColumnInfo{
+	{Keyspace: "myKeyspace", Table: "rows", Name: "id", TypeInfo: gocql.NewNativeType(4, gocql.TypeVarchar, "")},
+	{Keyspace: "myKeyspace", Table: "rows", Name: "name", TypeInfo: gocql.NewNativeType(4, gocql.TypeVarchar, "")},
+	{Keyspace: "myKeyspace", Table: "rows", Name: "score", TypeInfo: gocql.NewNativeType(4, gocql.TypeBigInt, "")},
+	{Keyspace: "myKeyspace", Table: "rows", Name: "tags", TypeInfo: gocql.CollectionType{
+		NativeType: gocql.NewNativeType(4, gocql.TypeList, ""),
+		Elem:       gocql.NewNativeType(4, gocql.TypeVarchar, ""),
+	}},
+	{Keyspace: "myKeyspace", Table: "rows", Name: "location", TypeInfo: gocql.UDTTypeInfo{
+		NativeType: gocql.NewNativeType(4, gocql.TypeUDT, ""),
+		KeySpace:   "myKeyspace",
+		Name:       "location",
+		Elements: []gocql.UDTField{
+			{Name: "lat", Type: gocql.NewNativeType(4, gocql.TypeDouble, "")},
+			{Name: "lon", Type: gocql.NewNativeType(4, gocql.TypeDouble, "")},
+		},
+	}},
+	{Keyspace: "myKeyspace", Table: "rows", Name: "updated_by", TypeInfo: gocql.NewNativeType(4, gocql.TypeVarchar, "")},
+}
+
+func rowValues(t *testing.T) [][]byte {
+	location, err := gocql.Marshal(rowColumns[4].TypeInfo, RowLocation{Lat: 50.1, Lon: 14.4})
+	require.NoError(t, err)
+	tags, err := gocql.Marshal(rowColumns[3].TypeInfo, []string{"a", "b"})
+	require.NoError(t, err)
+
+	return [][]byte{
+		[]byte("row1"),
+		[]byte("John"),
+		nil,
+		tags,
+		location,
+		[]byte("admin"),
+	}
+}
+
+func TestScanCQL(t *testing.T) {
+	t.Parallel()
+
+	var row RowStruct
+	err := row.ScanCQL(rowColumns, rowValues(t))
+	require.NoError(t, err)
+	require.Equal(t, RowStruct{
+		ID:       "row1",
+		Name:     "John",
+		Score:    10,
+		Tags:     []string{"a", "b"},
+		Location: RowLocation{Lat: 50.1, Lon: 14.4},
+		Extra:    map[string][]byte{"updated_by": []byte("admin")},
+	}, row)
+
+	values := rowValues(t)
+	values[1] = nil
+	err = row.ScanCQL(rowColumns, values)
+	require.EqualError(t, err, "column name: null value not allowed")
+
+	err = row.ScanCQL(rowColumns[1:], rowValues(t)[1:])
+	require.EqualError(t, err, "key 'id' is required")
+
+	err = row.ScanCQL(rowColumns, rowValues(t)[1:])
+	require.EqualError(t, err, "got 5 values for 6 columns")
+}
+
+// fakeScanner is gocql.Scanner returning serialized rows.
+type fakeScanner struct {
+	columns []gocql.ColumnInfo
+	rows    [][][]byte
+}
+
+func (s *fakeScanner) Next() bool {
+	return len(s.rows) > 0
+}
+
+func (s *fakeScanner) Scan(dest ...interface{}) error {
+	if len(dest) != len(s.columns) {
+		return errors.New("count mismatch")
+	}
+	row := s.rows[0]
+	s.rows = s.rows[1:]
+	for i := range dest {
+		if err := gocql.Unmarshal(s.columns[i].TypeInfo, row[i], dest[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *fakeScanner) Err() error {
+	return nil
+}
+
+func TestRowReader(t *testing.T) {
+	t.Parallel()
+
+	second := rowValues(t)
+	second[0] = []byte("row2")
+	scanner := &fakeScanner{columns: rowColumns, rows: [][][]byte{rowValues(t), second}}
+
+	reader, err := easycql.NewRowReader(rowColumns)
+	require.NoError(t, err)
+	var ids []string
+	for scanner.Next() {
+		var row RowStruct
+		require.NoError(t, reader.Scan(scanner, &row))
+		ids = append(ids, row.ID)
+	}
+	require.Equal(t, []string{"row1", "row2"}, ids)
+}
+
+func TestRowReaderTuple(t *testing.T) {
+	t.Parallel()
+
+	columns := []gocql.ColumnInfo{
+		{Name: "id", TypeInfo: gocql.NewNativeType(4, gocql.TypeVarchar, "")},
+		{Name: "point", TypeInfo: gocql.TupleTypeInfo{
+			NativeType: gocql.NewNativeType(4, gocql.TypeTuple, ""),
+			Elems:      []gocql.TypeInfo{gocql.NewNativeType(4, gocql.TypeInt, ""), gocql.NewNativeType(4, gocql.TypeInt, "")},
+		}},
+	}
+	_, err := easycql.NewRowReader(columns)
+	require.EqualError(t, err, "column point: tuple columns are not supported")
+}
+
+func BenchmarkScanCQL(b *testing.B) {
+	location := marshal.AppendBytes(nil, []byte{64, 73, 12, 204, 204, 204, 204, 205})
+	location = marshal.AppendBytes(location, []byte{64, 44, 204, 204, 204, 204, 204, 205})
+	values := [][]byte{[]byte("row1"), []byte("John"), {0, 0, 0, 0, 0, 0, 0, 1}, nil, location, nil}
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		var row RowStruct
+		if err := row.ScanCQL(rowColumns, values); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestScanCQLWritetimeTTL(t *testing.T) {
+	t.Parallel()
+
+	columns := []gocql.ColumnInfo{
+		{Name: "id", TypeInfo: gocql.NewNativeType(4, gocql.TypeVarchar, "")},
+		{Name: "price", TypeInfo: gocql.NewNativeType(4, gocql.TypeBigInt, "")},
+		{Name: "writetime(price)", TypeInfo: gocql.NewNativeType(4, gocql.TypeBigInt, "")},
+		{Name: "ttl(price)", TypeInfo: gocql.NewNativeType(4, gocql.TypeInt, "")},
+	}
+	writetime := time.Date(2021, 3, 4, 5, 6, 7, 123456000, time.UTC)
+	values := [][]byte{
+		[]byte("a"),
+		{0, 0, 0, 0, 0, 0, 0, 42},
+		{0x00, 0x05, 0xbc, 0xae, 0xec, 0xd2, 0xd0, 0x00},
+		{0, 0, 0x0e, 0x10},
+	}
+
+	var row RowMetaStruct
+	err := row.ScanCQL(columns, values)
+	require.NoError(t, err)
+	require.Equal(t, RowMetaStruct{ID: "a", Price: 42, PriceWritten: writetime, PriceTTL: time.Hour}, row)
+
+	var intRow RowMetaIntStruct
+	err = intRow.ScanCQL(columns, values)
+	require.NoError(t, err)
+	require.Equal(t, RowMetaIntStruct{PriceWritten: writetime.UnixNano() / 1000, PriceTTL: 3600}, intRow)
+
+	// ttl() is null when the column has no TTL.
+	values[3] = nil
+	err = row.ScanCQL(columns, values)
+	require.NoError(t, err)
+	require.Equal(t, time.Duration(0), row.PriceTTL)
+
+	values[2] = []byte{1}
+	err = row.ScanCQL(columns, values)
+	require.EqualError(t, err, "column writetime(price): expected 8 bytes, got 1")
+
+	// Meta fields of embedded and inlined structs are decoded too.
+	columns[3].Name = "ttl(discount_amount)"
+	values[2], values[3] = []byte{0x00, 0x05, 0xbc, 0xae, 0xec, 0xd2, 0xd0, 0x00}, []byte{0, 0, 0x0e, 0x10}
+	var embedded RowEmbeddedMetaStruct
+	err = embedded.ScanCQL(columns, values)
+	require.NoError(t, err)
+	require.Equal(t, writetime, embedded.PriceWritten)
+	require.Equal(t, time.Hour, embedded.Discount.AmountTTL)
+}
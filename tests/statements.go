@@ -0,0 +1,64 @@
+package tests
+
+import "time"
+
+type StatementStruct struct {
+	_        struct{}  `easycql:",table=shop.orders"`
+	Customer string    `easycql:"customer,pk"`
+	ID       int64     `easycql:"id,ck"`
+	Shard    int       `easycql:"shard,pk"`
+	Note     string    `easycql:"note,omitempty"`
+	Total    int64     `easycql:"Total"`
+	Created  time.Time `easycql:"created,bigint,codec=legacyTimeCodec"`
+	Internal string    `easycql:"-"`
+}
+
+type CollectionStatementStruct struct {
+	_      struct{}       `easycql:",table=carts"`
+	ID     string         `easycql:"id,pk"`
+	Items  []int64        `easycql:"items,list"`
+	Tags   []string       `easycql:"tags,type=set<text>"`
+	Labels []string       `easycql:"labels"`
+	Counts map[string]int `easycql:"Counts"`
+	Frozen []string       `easycql:"frozen,type=frozen<list<text>>"`
+	Limits map[string]int `easycql:"limits,type=frozen<map<text,int>>"`
+	Extra  []byte         `easycql:"extra,raw"`
+}
+
+type CounterStatementStruct struct {
+	_      struct{} `easycql:",table=page_stats,counters"`
+	Page   string   `easycql:"page,pk"`
+	Day    int      `easycql:"day,ck"`
+	Views  int64    `easycql:"views,counter"`
+	Clicks int      `easycql:"clicks,counter"`
+}
+
+type MetaStatementStruct struct {
+	_            struct{}      `easycql:",table=prices"`
+	ID           string        `easycql:"id,pk"`
+	Price        int64         `easycql:"price"`
+	PriceWritten time.Time     `easycql:"price,writetime"`
+	PriceTTL     time.Duration `easycql:"price,ttl"`
+}
+
+type KeyOnlyStatementStruct struct {
+	_  struct{} `easycql:",table=tags"`
+	ID string   `easycql:"id,pk"`
+}
+
+type MetaEmbedded struct {
+	PriceWritten time.Time `easycql:"price,writetime"`
+}
+
+type MetaInline struct {
+	Amount    int64         `easycql:"amount"`
+	AmountTTL time.Duration `easycql:"amount,ttl"`
+}
+
+type EmbeddedMetaStatementStruct struct {
+	_  struct{} `easycql:",table=prices"`
+	ID string   `easycql:"id,pk"`
+	MetaEmbedded
+	Price    int64      `easycql:"price"`
+	Discount MetaInline `easycql:",inline=discount_"`
+}
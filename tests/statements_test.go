@@ -0,0 +1,148 @@
+package tests
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/gocql/gocql"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kiwicom/easycql"
+)
+
+func TestStatements(t *testing.T) {
+	t.Parallel()
+
+	value := StatementStruct{Customer: "john", ID: 7, Shard: 2, Total: 100, Created: time.Unix(1600000000, 0)}
+
+	require.Equal(t, `INSERT INTO shop.orders (note, "Total", created, customer, shard, id) VALUES (?, ?, ?, ?, ?, ?)`,
+		value.InsertCQL(easycql.Using{}))
+	require.Equal(t, `INSERT INTO shop.orders (note, "Total", created, customer, shard, id) VALUES (?, ?, ?, ?, ?, ?)`+
+		` USING TTL ? AND TIMESTAMP ?`, value.InsertCQL(easycql.Using{TTL: time.Hour, Timestamp: 1}))
+	require.Equal(t, `UPDATE shop.orders USING TTL ? SET note = ?, "Total" = ?, created = ? `+
+		`WHERE customer = ? AND shard = ? AND id = ?`, value.UpdateCQL(easycql.Using{TTL: time.Hour}))
+	require.Equal(t, `SELECT note, "Total", created, customer, shard, id FROM shop.orders `+
+		`WHERE customer = ? AND shard = ? AND id = ?`, value.SelectByKeyCQL())
+	require.Equal(t, `DELETE FROM shop.orders WHERE customer = ? AND shard = ? AND id = ?`, value.DeleteCQL())
+
+	require.Equal(t, []interface{}{"john", 2, int64(7)}, value.KeyBindValues())
+
+	types := []gocql.TypeInfo{
+		gocql.NewNativeType(4, gocql.TypeVarchar, ""),
+		gocql.NewNativeType(4, gocql.TypeBigInt, ""),
+		gocql.NewNativeType(4, gocql.TypeBigInt, ""),
+		gocql.NewNativeType(4, gocql.TypeVarchar, ""),
+		gocql.NewNativeType(4, gocql.TypeInt, ""),
+		gocql.NewNativeType(4, gocql.TypeBigInt, ""),
+	}
+	expected := []interface{}{nil, int64(100), int64(1600000000), "john", 2, int64(7)}
+
+	values := value.BindValues()
+	require.Len(t, values, len(expected))
+	for i := range values {
+		// Values are bound the same way as gocql binds query arguments.
+		data, err := gocql.Marshal(types[i], values[i])
+		require.NoError(t, err)
+		expectedData, err := gocql.Marshal(types[i], expected[i])
+		require.NoError(t, err)
+		require.Equal(t, expectedData, data, i)
+	}
+
+	keyOnly := KeyOnlyStatementStruct{ID: "a"}
+	require.Equal(t, `INSERT INTO tags (id) VALUES (?)`, keyOnly.InsertCQL(easycql.Using{}))
+	require.Equal(t, []interface{}{"a"}, keyOnly.BindValues())
+	require.Empty(t, keyOnly.UpdateCQL(easycql.Using{}))
+
+	// Raw values are bound as they are.
+	raw := CollectionStatementStruct{Extra: []byte{0, 0, 0, 1}}
+	data, err := gocql.Marshal(gocql.NewNativeType(4, gocql.TypeInt, ""), raw.BindValues()[6])
+	require.NoError(t, err)
+	require.Equal(t, raw.Extra, data)
+}
+
+func TestCollectionMutations(t *testing.T) {
+	t.Parallel()
+
+	var v CollectionStatementStruct
+	tests := []struct {
+		fragment       string
+		values         []interface{}
+		expectedFrag   string
+		expectedValues []interface{}
+	}{
+		{expectedFrag: "items = items + ?", expectedValues: []interface{}{[]int64{1, 2}}},
+		{expectedFrag: "items = ? + items", expectedValues: []interface{}{[]int64{3}}},
+		{expectedFrag: "items = items - ?", expectedValues: []interface{}{[]int64{4}}},
+		{expectedFrag: "tags = tags + ?", expectedValues: []interface{}{[]string{"a"}}},
+		{expectedFrag: "tags = tags - ?", expectedValues: []interface{}{[]string{"b"}}},
+		{expectedFrag: "labels = labels + ?", expectedValues: []interface{}{[]string{"c"}}},
+		{expectedFrag: `"Counts"[?] = ?`, expectedValues: []interface{}{"x", 1}},
+		{expectedFrag: `"Counts"[?]`, expectedValues: []interface{}{"y"}},
+	}
+	tests[0].fragment, tests[0].values = v.AppendItemsCQL(1, 2)
+	tests[1].fragment, tests[1].values = v.PrependItemsCQL(3)
+	tests[2].fragment, tests[2].values = v.RemoveItemsCQL(4)
+	tests[3].fragment, tests[3].values = v.AddTagsCQL("a")
+	tests[4].fragment, tests[4].values = v.RemoveTagsCQL("b")
+	tests[5].fragment, tests[5].values = v.AppendLabelsCQL("c")
+	tests[6].fragment, tests[6].values = v.PutCountsCQL("x", 1)
+	tests[7].fragment, tests[7].values = v.DeleteCountsCQL("y")
+
+	for _, test := range tests {
+		require.Equal(t, test.expectedFrag, test.fragment)
+		require.Equal(t, test.expectedValues, test.values)
+	}
+
+	// Labels have no CQL type in tags, so both list and set methods are generated.
+	fragment, _ := v.AddLabelsCQL("d")
+	require.Equal(t, "labels = labels + ?", fragment)
+	fragment, _ = v.PrependLabelsCQL("e")
+	require.Equal(t, "labels = ? + labels", fragment)
+
+	// Frozen collections can only be replaced as a whole.
+	typ := reflect.TypeOf(&v)
+	for _, name := range []string{"AppendFrozenCQL", "PrependFrozenCQL", "AddFrozenCQL", "RemoveFrozenCQL",
+		"PutLimitsCQL", "DeleteLimitsCQL"} {
+		_, ok := typ.MethodByName(name)
+		require.False(t, ok, name)
+	}
+}
+
+func TestCounterIncrement(t *testing.T) {
+	t.Parallel()
+
+	value := CounterStatementStruct{Page: "home", Day: 3}
+
+	stmt, values := value.IncrementCQL(CounterStatementStructDelta{Views: 2, Clicks: -1})
+	require.Equal(t, "UPDATE page_stats SET views = views + ?, clicks = clicks + ? WHERE page = ? AND day = ?", stmt)
+	require.Equal(t, []interface{}{int64(2), int64(-1), "home", 3}, values)
+
+	stmt, values = value.IncrementCQL(CounterStatementStructDelta{Clicks: 1})
+	require.Equal(t, "UPDATE page_stats SET clicks = clicks + ? WHERE page = ? AND day = ?", stmt)
+	require.Equal(t, []interface{}{int64(1), "home", 3}, values)
+
+	stmt, values = value.IncrementCQL(CounterStatementStructDelta{})
+	require.Empty(t, stmt)
+	require.Nil(t, values)
+
+	require.Equal(t, "SELECT views, clicks, page, day FROM page_stats WHERE page = ? AND day = ?", value.SelectByKeyCQL())
+}
+
+func TestStatementsWritetimeTTL(t *testing.T) {
+	t.Parallel()
+
+	var value MetaStatementStruct
+	require.Equal(t, "SELECT price, id, writetime(price), ttl(price) FROM prices WHERE id = ?", value.SelectByKeyCQL())
+	require.Equal(t, "INSERT INTO prices (price, id) VALUES (?, ?)", value.InsertCQL(easycql.Using{}))
+	require.Len(t, value.BindValues(), 2)
+}
+
+func TestStatementsEmbeddedWritetimeTTL(t *testing.T) {
+	t.Parallel()
+
+	var value EmbeddedMetaStatementStruct
+	require.Equal(t, "SELECT price, discount_amount, id, writetime(price), ttl(discount_amount) FROM prices "+
+		"WHERE id = ?", value.SelectByKeyCQL())
+
+}
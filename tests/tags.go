@@ -0,0 +1,108 @@
+package tests
+
+import (
+	"time"
+
+	"github.com/gocql/gocql"
+
+	"github.com/kiwicom/easycql/marshal"
+	"github.com/kiwicom/easycql/tests/external"
+)
+
+type CodecStruct struct {
+	LegacyTS time.Time `easycql:"legacy_ts,bigint,codec=legacyTimeCodec"`
+	Value    string    `easycql:"value"`
+}
+
+// legacyTimeCodecEncode stores time as epoch seconds.
+func legacyTimeCodecEncode(info gocql.TypeInfo, in time.Time) ([]byte, error) {
+	return gocql.Marshal(info, in.Unix())
+}
+
+// legacyTimeCodecDecode reads time stored as epoch seconds.
+func legacyTimeCodecDecode(info gocql.TypeInfo, data []byte, out *time.Time) error {
+	if data == nil {
+		*out = time.Time{}
+		return nil
+	}
+	*out = time.Unix(marshal.DecBigInt(data), 0).UTC()
+	return nil
+}
+
+type UDTMarshalerStruct struct {
+	Address    LegacyAddress  `easycql:"address"`
+	AddressPtr *LegacyAddress `easycql:"address_ptr"`
+}
+
+type OmitEmptyStruct struct {
+	String string     `easycql:"string,omitempty"`
+	Int    int        `easycql:"int,omitempty"`
+	Time   time.Time  `easycql:"time,omitempty"`
+	Slice  []string   `easycql:"slice,omitempty"`
+	Ptr    *int       `easycql:"ptr,omitempty"`
+	UUID   gocql.UUID `easycql:"uuid,omitempty"`
+	Always string     `easycql:"always"`
+}
+
+type DefaultStruct struct {
+	// Missing fields only get defaults in reset mode.
+	_        struct{}      `easycql:",decode=reset"`
+	String   string        `easycql:"string,default=unknown"`
+	Int      int           `easycql:"int,default=42"`
+	IntPtr   *int          `easycql:"int_ptr,default=-7"`
+	Bool     bool          `easycql:"bool,default=true"`
+	Float    float64       `easycql:"float,default=1.5"`
+	Duration time.Duration `easycql:"duration,default=1m30s"`
+	Value    string        `easycql:"value"`
+}
+
+type NotNullStruct struct {
+	_         struct{} `easycql:",decode=reset"`
+	BookingID string   `easycql:"booking_id,notnull"`
+	Amount    int64    `easycql:"amount,notnull,default=1"`
+	Note      string   `easycql:"note"`
+}
+
+type RenamedFieldStruct struct {
+	NewName string `easycql:"new_name|old_name,required"`
+	Value   int    `easycql:"value"`
+}
+
+type RequiredStruct struct {
+	ID      string   `easycql:"id,required=nonzero"`
+	Comment *string  `easycql:"comment,required"`
+	Tags    []string `easycql:"tags,required"`
+	Count   int      `easycql:"count,required"`
+}
+
+type RawStruct struct {
+	ID      int    `easycql:"id"`
+	Payload []byte `easycql:"payload,raw"`
+}
+
+type Money struct {
+	Amount   int64  `easycql:"amount,required"`
+	Currency string `easycql:"currency"`
+}
+
+type InlineStruct struct {
+	ID       int   `easycql:"id"`
+	Price    Money `easycql:",inline=price_"`
+	Discount Money `easycql:",inline"`
+}
+
+type UnexportedStruct struct {
+	Name    string `easycql:"name"`
+	balance int64  `easycql:"balance"`
+	hidden  int64
+	tagged  int64 `cql:"tagged"`
+}
+
+type ExternalEmbedStruct struct {
+	external.Base
+	Name string `easycql:"name"`
+}
+
+func (s UnexportedStruct) Balance() int64 {
+	return s.balance
+}
@@ -0,0 +1,739 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gocql/gocql"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kiwicom/easycql"
+	"github.com/kiwicom/easycql/marshal"
+	"github.com/kiwicom/easycql/tests/external"
+)
+
+func TestCodec(t *testing.T) {
+	t.Parallel()
+
+	typeInfo := gocql.UDTTypeInfo{
+		NativeType: gocql.NewNativeType(3, gocql.TypeUDT, ""),
+		KeySpace:   "myKeyspace",
+		Name:       "CodecUDT",
+		Elements: []gocql.UDTField{
+			{
+				Name: "legacy_ts",
+				Type: gocql.NewNativeType(3, gocql.TypeBigInt, ""),
+			},
+			{
+				Name: "value",
+				Type: gocql.NewNativeType(3, gocql.TypeVarchar, ""),
+			},
+		},
+	}
+	var expectedData []byte
+	expectedData = marshal.AppendBytes(expectedData, []byte{0, 0, 0, 0, 0x5f, 0x5e, 0x10, 0})
+	expectedData = marshal.AppendBytes(expectedData, []byte("hello"))
+	value := CodecStruct{
+		LegacyTS: time.Unix(0x5f5e1000, 0).UTC(),
+		Value:    "hello",
+	}
+
+	t.Run("marshal", func(t *testing.T) {
+		t.Parallel()
+		data, err := gocql.Marshal(typeInfo, value)
+		require.NoError(t, err)
+		require.Equal(t, expectedData, data)
+	})
+
+	t.Run("unmarshal", func(t *testing.T) {
+		t.Parallel()
+		var unmarshaled CodecStruct
+		err := gocql.Unmarshal(typeInfo, expectedData, &unmarshaled)
+		require.NoError(t, err)
+		require.Equal(t, value, unmarshaled)
+	})
+}
+
+func TestUDTMarshalerField(t *testing.T) {
+	t.Parallel()
+
+	addressTypeInfo := gocql.UDTTypeInfo{
+		NativeType: gocql.NewNativeType(3, gocql.TypeUDT, ""),
+		KeySpace:   "myKeyspace",
+		Name:       "AddressUDT",
+		Elements: []gocql.UDTField{
+			{
+				Name: "street",
+				Type: gocql.NewNativeType(3, gocql.TypeVarchar, ""),
+			},
+			{
+				Name: "city",
+				Type: gocql.NewNativeType(3, gocql.TypeVarchar, ""),
+			},
+		},
+	}
+	typeInfo := gocql.UDTTypeInfo{
+		NativeType: gocql.NewNativeType(3, gocql.TypeUDT, ""),
+		KeySpace:   "myKeyspace",
+		Name:       "UDTMarshalerUDT",
+		Elements: []gocql.UDTField{
+			{
+				Name: "address",
+				Type: addressTypeInfo,
+			},
+			{
+				Name: "address_ptr",
+				Type: addressTypeInfo,
+			},
+		},
+	}
+	var addressData []byte
+	addressData = marshal.AppendBytes(addressData, []byte("Main street"))
+	addressData = marshal.AppendBytes(addressData, []byte("Brno"))
+	var expectedData []byte
+	expectedData = marshal.AppendBytes(expectedData, addressData)
+	expectedData = marshal.AppendBytes(expectedData, nil)
+	value := UDTMarshalerStruct{
+		Address: LegacyAddress{Street: "Main street", City: "Brno"},
+	}
+
+	t.Run("marshal", func(t *testing.T) {
+		t.Parallel()
+		data, err := gocql.Marshal(typeInfo, value)
+		require.NoError(t, err)
+		require.Equal(t, expectedData, data)
+	})
+
+	t.Run("unmarshal", func(t *testing.T) {
+		t.Parallel()
+		var unmarshaled UDTMarshalerStruct
+		err := gocql.Unmarshal(typeInfo, expectedData, &unmarshaled)
+		require.NoError(t, err)
+		require.Equal(t, value, unmarshaled)
+	})
+}
+
+func TestUDTMethods(t *testing.T) {
+	t.Parallel()
+
+	value := UDTMethodsStruct{Name: "hello", Count: 42}
+	varcharInfo := gocql.NewNativeType(3, gocql.TypeVarchar, "")
+	intInfo := gocql.NewNativeType(3, gocql.TypeInt, "")
+
+	t.Run("marshal", func(t *testing.T) {
+		t.Parallel()
+		data, err := value.MarshalUDT("name", varcharInfo)
+		require.NoError(t, err)
+		require.Equal(t, []byte("hello"), data)
+
+		data, err = value.MarshalUDT("count", intInfo)
+		require.NoError(t, err)
+		require.Equal(t, []byte{0, 0, 0, 42}, data)
+
+		data, err = value.MarshalUDT("unknown", intInfo)
+		require.NoError(t, err)
+		require.Nil(t, data)
+	})
+
+	t.Run("unmarshal", func(t *testing.T) {
+		t.Parallel()
+		var unmarshaled UDTMethodsStruct
+		require.NoError(t, unmarshaled.UnmarshalUDT("name", varcharInfo, []byte("hello")))
+		require.NoError(t, unmarshaled.UnmarshalUDT("count", intInfo, []byte{0, 0, 0, 42}))
+		require.NoError(t, unmarshaled.UnmarshalUDT("unknown", intInfo, []byte{0, 0, 0, 1}))
+		require.Equal(t, value, unmarshaled)
+	})
+}
+
+func TestMarshalOmitEmpty(t *testing.T) {
+	t.Parallel()
+
+	typeInfo := gocql.UDTTypeInfo{
+		NativeType: gocql.NewNativeType(3, gocql.TypeUDT, ""),
+		KeySpace:   "myKeyspace",
+		Name:       "OmitEmptyUDT",
+		Elements: []gocql.UDTField{
+			{Name: "string", Type: gocql.NewNativeType(3, gocql.TypeVarchar, "")},
+			{Name: "int", Type: gocql.NewNativeType(3, gocql.TypeInt, "")},
+			{Name: "time", Type: gocql.NewNativeType(3, gocql.TypeTimestamp, "")},
+			{
+				Name: "slice",
+				Type: gocql.CollectionType{
+					NativeType: gocql.NewNativeType(3, gocql.TypeList, ""),
+					Elem:       gocql.NewNativeType(3, gocql.TypeVarchar, ""),
+				},
+			},
+			{Name: "ptr", Type: gocql.NewNativeType(3, gocql.TypeInt, "")},
+			{Name: "uuid", Type: gocql.NewNativeType(3, gocql.TypeUUID, "")},
+			{Name: "always", Type: gocql.NewNativeType(3, gocql.TypeVarchar, "")},
+		},
+	}
+
+	t.Run("empty", func(t *testing.T) {
+		t.Parallel()
+		var expectedData []byte
+		for i := 0; i < 6; i++ {
+			expectedData = marshal.AppendBytes(expectedData, nil)
+		}
+		expectedData = marshal.AppendBytes(expectedData, []byte{})
+		data, err := gocql.Marshal(typeInfo, OmitEmptyStruct{Slice: []string{}})
+		require.NoError(t, err)
+		require.Equal(t, expectedData, data)
+	})
+
+	t.Run("non-empty", func(t *testing.T) {
+		t.Parallel()
+		value := OmitEmptyStruct{
+			String: "a",
+			Int:    1,
+			Time:   time.Unix(1, 0),
+			Slice:  []string{"b"},
+			Ptr:    newIntPtr(0),
+			UUID:   gocql.UUID{1},
+			Always: "c",
+		}
+		var expectedData []byte
+		expectedData = marshal.AppendBytes(expectedData, []byte("a"))
+		expectedData = marshal.AppendBytes(expectedData, []byte{0, 0, 0, 1})
+		expectedData = marshal.AppendBytes(expectedData, []byte{0, 0, 0, 0, 0, 0, 0x03, 0xe8})
+		expectedData = marshal.AppendBytes(expectedData, []byte("\x00\x00\x00\x01\x00\x00\x00\x01b"))
+		expectedData = marshal.AppendBytes(expectedData, []byte{0, 0, 0, 0})
+		expectedData = marshal.AppendBytes(expectedData, value.UUID.Bytes())
+		expectedData = marshal.AppendBytes(expectedData, []byte("c"))
+		data, err := gocql.Marshal(typeInfo, value)
+		require.NoError(t, err)
+		require.Equal(t, expectedData, data)
+	})
+}
+
+func TestUnmarshalDefault(t *testing.T) {
+	t.Parallel()
+
+	typeInfo := gocql.UDTTypeInfo{
+		NativeType: gocql.NewNativeType(3, gocql.TypeUDT, ""),
+		KeySpace:   "myKeyspace",
+		Name:       "DefaultUDT",
+		Elements: []gocql.UDTField{
+			{Name: "string", Type: gocql.NewNativeType(3, gocql.TypeVarchar, "")},
+			{Name: "int_ptr", Type: gocql.NewNativeType(3, gocql.TypeInt, "")},
+			{Name: "value", Type: gocql.NewNativeType(3, gocql.TypeVarchar, "")},
+			{Name: "bool", Type: gocql.NewNativeType(3, gocql.TypeBoolean, "")},
+		},
+	}
+	expected := DefaultStruct{
+		String:   "unknown",
+		Int:      42,
+		IntPtr:   newIntPtr(-7),
+		Bool:     true,
+		Float:    1.5,
+		Duration: 90 * time.Second,
+		Value:    "hello",
+	}
+
+	t.Run("null", func(t *testing.T) {
+		t.Parallel()
+		var data []byte
+		data = marshal.AppendBytes(data, nil)
+		data = marshal.AppendBytes(data, nil)
+		data = marshal.AppendBytes(data, []byte("hello"))
+		data = marshal.AppendBytes(data, nil)
+		var value DefaultStruct
+		err := gocql.Unmarshal(typeInfo, data, &value)
+		require.NoError(t, err)
+		require.Equal(t, expected, value)
+	})
+
+	t.Run("missing", func(t *testing.T) {
+		t.Parallel()
+		var data []byte
+		data = marshal.AppendBytes(data, nil)
+		data = marshal.AppendBytes(data, nil)
+		data = marshal.AppendBytes(data, []byte("hello"))
+		value := DefaultStruct{Bool: false, Int: 1}
+		err := gocql.Unmarshal(typeInfo, data, &value)
+		require.NoError(t, err)
+		require.Equal(t, expected, value)
+	})
+
+	t.Run("set", func(t *testing.T) {
+		t.Parallel()
+		var data []byte
+		data = marshal.AppendBytes(data, []byte("known"))
+		data = marshal.AppendBytes(data, []byte{0, 0, 0, 1})
+		data = marshal.AppendBytes(data, []byte("hello"))
+		data = marshal.AppendBytes(data, []byte{0})
+		var value DefaultStruct
+		err := gocql.Unmarshal(typeInfo, data, &value)
+		require.NoError(t, err)
+		require.Equal(t, DefaultStruct{
+			String:   "known",
+			Int:      42,
+			IntPtr:   newIntPtr(1),
+			Bool:     false,
+			Float:    1.5,
+			Duration: 90 * time.Second,
+			Value:    "hello",
+		}, value)
+	})
+}
+
+func TestUnmarshalNotNull(t *testing.T) {
+	t.Parallel()
+
+	typeInfo := gocql.UDTTypeInfo{
+		NativeType: gocql.NewNativeType(3, gocql.TypeUDT, ""),
+		KeySpace:   "myKeyspace",
+		Name:       "NotNullUDT",
+		Elements: []gocql.UDTField{
+			{Name: "booking_id", Type: gocql.NewNativeType(3, gocql.TypeVarchar, "")},
+			{Name: "amount", Type: gocql.NewNativeType(3, gocql.TypeBigInt, "")},
+			{Name: "note", Type: gocql.NewNativeType(3, gocql.TypeVarchar, "")},
+		},
+	}
+
+	for _, test := range []struct {
+		Name  string
+		Data  [][]byte
+		Value NotNullStruct
+		Error string
+	}{
+		{
+			Name:  "set",
+			Data:  [][]byte{[]byte("abc"), {0, 0, 0, 0, 0, 0, 0, 5}, nil},
+			Value: NotNullStruct{BookingID: "abc", Amount: 5},
+		},
+		{
+			Name:  "missing",
+			Data:  [][]byte{[]byte("abc")},
+			Value: NotNullStruct{BookingID: "abc", Amount: 1},
+		},
+		{
+			Name:  "null string",
+			Data:  [][]byte{nil, {0, 0, 0, 0, 0, 0, 0, 5}, nil},
+			Error: "NotNullUDT.booking_id UDT unmarshal: null value not allowed",
+		},
+		{
+			Name:  "null int",
+			Data:  [][]byte{[]byte("abc"), nil, nil},
+			Error: "NotNullUDT.amount UDT unmarshal: null value not allowed",
+		},
+	} {
+		test := test
+		t.Run(test.Name, func(t *testing.T) {
+			t.Parallel()
+			var data []byte
+			for _, elementData := range test.Data {
+				data = marshal.AppendBytes(data, elementData)
+			}
+			var value NotNullStruct
+			err := gocql.Unmarshal(typeInfo, data, &value)
+			if test.Error != "" {
+				require.EqualError(t, err, test.Error)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, test.Value, value)
+		})
+	}
+}
+
+func TestUDTMethodsNotNull(t *testing.T) {
+	t.Parallel()
+
+	var value UDTMethodsNotNullStruct
+	err := value.UnmarshalUDT("name", gocql.NewNativeType(3, gocql.TypeVarchar, ""), nil)
+	require.EqualError(t, err, "name UDT unmarshal: null value not allowed")
+}
+
+func TestRenamedField(t *testing.T) {
+	t.Parallel()
+
+	for _, name := range []string{"new_name", "old_name"} {
+		name := name
+		typeInfo := gocql.UDTTypeInfo{
+			NativeType: gocql.NewNativeType(3, gocql.TypeUDT, ""),
+			KeySpace:   "myKeyspace",
+			Name:       "RenamedFieldUDT",
+			Elements: []gocql.UDTField{
+				{Name: name, Type: gocql.NewNativeType(3, gocql.TypeVarchar, "")},
+				{Name: "value", Type: gocql.NewNativeType(3, gocql.TypeInt, "")},
+			},
+		}
+		var expectedData []byte
+		expectedData = marshal.AppendBytes(expectedData, []byte("hello"))
+		expectedData = marshal.AppendBytes(expectedData, []byte{0, 0, 0, 42})
+		value := RenamedFieldStruct{NewName: "hello", Value: 42}
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			data, err := gocql.Marshal(typeInfo, value)
+			require.NoError(t, err)
+			require.Equal(t, expectedData, data)
+
+			var unmarshaled RenamedFieldStruct
+			err = gocql.Unmarshal(typeInfo, expectedData, &unmarshaled)
+			require.NoError(t, err)
+			require.Equal(t, value, unmarshaled)
+		})
+	}
+}
+
+func TestInline(t *testing.T) {
+	t.Parallel()
+
+	typeInfo := gocql.UDTTypeInfo{
+		NativeType: gocql.NewNativeType(3, gocql.TypeUDT, ""),
+		KeySpace:   "myKeyspace",
+		Name:       "InlineUDT",
+		Elements: []gocql.UDTField{
+			{Name: "id", Type: gocql.NewNativeType(3, gocql.TypeInt, "")},
+			{Name: "price_amount", Type: gocql.NewNativeType(3, gocql.TypeBigInt, "")},
+			{Name: "price_currency", Type: gocql.NewNativeType(3, gocql.TypeVarchar, "")},
+			{Name: "amount", Type: gocql.NewNativeType(3, gocql.TypeBigInt, "")},
+			{Name: "currency", Type: gocql.NewNativeType(3, gocql.TypeVarchar, "")},
+		},
+	}
+	var expectedData []byte
+	expectedData = marshal.AppendBytes(expectedData, []byte{0, 0, 0, 1})
+	expectedData = marshal.AppendBytes(expectedData, []byte{0, 0, 0, 0, 0, 0, 0, 100})
+	expectedData = marshal.AppendBytes(expectedData, []byte("EUR"))
+	expectedData = marshal.AppendBytes(expectedData, []byte{0, 0, 0, 0, 0, 0, 0, 10})
+	expectedData = marshal.AppendBytes(expectedData, []byte("USD"))
+	value := InlineStruct{
+		ID:       1,
+		Price:    Money{Amount: 100, Currency: "EUR"},
+		Discount: Money{Amount: 10, Currency: "USD"},
+	}
+
+	data, err := gocql.Marshal(typeInfo, value)
+	require.NoError(t, err)
+	require.Equal(t, expectedData, data)
+
+	var unmarshaled InlineStruct
+	err = gocql.Unmarshal(typeInfo, expectedData, &unmarshaled)
+	require.NoError(t, err)
+	require.Equal(t, value, unmarshaled)
+
+	// price_amount is required through the inlined Money struct.
+	typeInfo.Elements = typeInfo.Elements[:1]
+	err = gocql.Unmarshal(typeInfo, expectedData[:8], &unmarshaled)
+	require.EqualError(t, err, "key 'price_amount' is required")
+}
+
+func TestUnexportedField(t *testing.T) {
+	t.Parallel()
+
+	typeInfo := gocql.UDTTypeInfo{
+		NativeType: gocql.NewNativeType(3, gocql.TypeUDT, ""),
+		KeySpace:   "myKeyspace",
+		Name:       "UnexportedUDT",
+		Elements: []gocql.UDTField{
+			{Name: "name", Type: gocql.NewNativeType(3, gocql.TypeVarchar, "")},
+			{Name: "balance", Type: gocql.NewNativeType(3, gocql.TypeBigInt, "")},
+			{Name: "hidden", Type: gocql.NewNativeType(3, gocql.TypeBigInt, "")},
+			{Name: "tagged", Type: gocql.NewNativeType(3, gocql.TypeBigInt, "")},
+		},
+	}
+	var expectedData []byte
+	expectedData = marshal.AppendBytes(expectedData, []byte("hello"))
+	expectedData = marshal.AppendBytes(expectedData, []byte{0, 0, 0, 0, 0, 0, 0, 42})
+	expectedData = marshal.AppendBytes(expectedData, nil)
+	expectedData = marshal.AppendBytes(expectedData, nil)
+	value := UnexportedStruct{Name: "hello", balance: 42, hidden: 7, tagged: 3}
+
+	data, err := gocql.Marshal(typeInfo, value)
+	require.NoError(t, err)
+	require.Equal(t, expectedData, data)
+
+	var unmarshaled UnexportedStruct
+	err = gocql.Unmarshal(typeInfo, expectedData, &unmarshaled)
+	require.NoError(t, err)
+	require.Equal(t, UnexportedStruct{Name: "hello", balance: 42}, unmarshaled)
+	require.Equal(t, int64(42), unmarshaled.Balance())
+}
+
+func TestUnexportedFieldOtherPackage(t *testing.T) {
+	t.Parallel()
+
+	typeInfo := gocql.UDTTypeInfo{
+		NativeType: gocql.NewNativeType(3, gocql.TypeUDT, ""),
+		KeySpace:   "myKeyspace",
+		Name:       "ExternalUDT",
+		Elements: []gocql.UDTField{
+			{Name: "id", Type: gocql.NewNativeType(3, gocql.TypeVarchar, "")},
+			{Name: "secret", Type: gocql.NewNativeType(3, gocql.TypeVarchar, "")},
+			{Name: "note", Type: gocql.NewNativeType(3, gocql.TypeVarchar, "")},
+			{Name: "name", Type: gocql.NewNativeType(3, gocql.TypeVarchar, "")},
+		},
+	}
+	var expectedData []byte
+	expectedData = marshal.AppendBytes(expectedData, []byte("1"))
+	expectedData = marshal.AppendBytes(expectedData, nil)
+	expectedData = marshal.AppendBytes(expectedData, nil)
+	expectedData = marshal.AppendBytes(expectedData, []byte("hello"))
+	value := ExternalEmbedStruct{Base: external.NewBase("1", "secret", "note"), Name: "hello"}
+
+	data, err := gocql.Marshal(typeInfo, value)
+	require.NoError(t, err)
+	require.Equal(t, expectedData, data)
+
+	var unmarshaled ExternalEmbedStruct
+	err = gocql.Unmarshal(typeInfo, expectedData, &unmarshaled)
+	require.NoError(t, err)
+	require.Equal(t, ExternalEmbedStruct{Base: external.NewBase("1", "", ""), Name: "hello"}, unmarshaled)
+}
+
+func TestUnknownFieldsCapture(t *testing.T) {
+	t.Parallel()
+
+	newTypeInfo := gocql.UDTTypeInfo{
+		NativeType: gocql.NewNativeType(3, gocql.TypeUDT, ""),
+		KeySpace:   "myKeyspace",
+		Name:       "UnknownUDT",
+		Elements: []gocql.UDTField{
+			{Name: "name", Type: gocql.NewNativeType(3, gocql.TypeVarchar, "")},
+			{Name: "added", Type: gocql.NewNativeType(3, gocql.TypeInt, "")},
+			{Name: "empty", Type: gocql.NewNativeType(3, gocql.TypeVarchar, "")},
+			{Name: "null", Type: gocql.NewNativeType(3, gocql.TypeVarchar, "")},
+		},
+	}
+	var data []byte
+	data = marshal.AppendBytes(data, []byte("hello"))
+	data = marshal.AppendBytes(data, []byte{0, 0, 0, 42})
+	data = marshal.AppendBytes(data, []byte{})
+	data = marshal.AppendBytes(data, nil)
+
+	var value UnknownCaptureStruct
+	err := gocql.Unmarshal(newTypeInfo, data, &value)
+	require.NoError(t, err)
+	require.Equal(t, UnknownCaptureStruct{
+		Name: "hello",
+		Unknown: map[string][]byte{
+			"added": {0, 0, 0, 42},
+			"empty": {},
+			"null":  nil,
+		},
+	}, value)
+
+	// Fields unknown to the struct round-trip untouched.
+	marshaled, err := gocql.Marshal(newTypeInfo, value)
+	require.NoError(t, err)
+	require.Equal(t, data, marshaled)
+
+	t.Run("udt methods", func(t *testing.T) {
+		t.Parallel()
+		intInfo := gocql.NewNativeType(3, gocql.TypeInt, "")
+
+		var unmarshaled UnknownCaptureStruct
+		require.NoError(t, unmarshaled.UnmarshalUDT("added", intInfo, []byte{0, 0, 0, 42}))
+		require.Equal(t, map[string][]byte{"added": {0, 0, 0, 42}}, unmarshaled.Unknown)
+
+		data, err := unmarshaled.MarshalUDT("added", intInfo)
+		require.NoError(t, err)
+		require.Equal(t, []byte{0, 0, 0, 42}, data)
+	})
+}
+
+func TestUnknownFieldsError(t *testing.T) {
+	t.Parallel()
+
+	typeInfo := gocql.UDTTypeInfo{
+		NativeType: gocql.NewNativeType(3, gocql.TypeUDT, ""),
+		KeySpace:   "myKeyspace",
+		Name:       "UnknownUDT",
+		Elements: []gocql.UDTField{
+			{Name: "name", Type: gocql.NewNativeType(3, gocql.TypeVarchar, "")},
+			{Name: "added", Type: gocql.NewNativeType(3, gocql.TypeInt, "")},
+		},
+	}
+	var data []byte
+	data = marshal.AppendBytes(data, []byte("hello"))
+	data = marshal.AppendBytes(data, []byte{0, 0, 0, 42})
+
+	var value UnknownErrorStruct
+	err := gocql.Unmarshal(typeInfo, data, &value)
+	require.EqualError(t, err, "unknown field: added")
+
+	_, err = gocql.Marshal(typeInfo, UnknownErrorStruct{Name: "hello"})
+	require.EqualError(t, err, "unknown field: added")
+
+	err = value.UnmarshalUDT("added", gocql.NewNativeType(3, gocql.TypeInt, ""), []byte{0, 0, 0, 42})
+	require.EqualError(t, err, "unknown field: added")
+
+	_, err = value.MarshalUDT("added", gocql.NewNativeType(3, gocql.TypeInt, ""))
+	require.EqualError(t, err, "unknown field: added")
+}
+
+func TestFieldPresence(t *testing.T) {
+	t.Parallel()
+
+	typeInfo := gocql.UDTTypeInfo{
+		NativeType: gocql.NewNativeType(3, gocql.TypeUDT, ""),
+		KeySpace:   "myKeyspace",
+		Name:       "PresenceUDT",
+		Elements: []gocql.UDTField{
+			{Name: "name", Type: gocql.NewNativeType(3, gocql.TypeVarchar, "")},
+			{Name: "count", Type: gocql.NewNativeType(3, gocql.TypeInt, "")},
+		},
+	}
+	var data []byte
+	data = marshal.AppendBytes(data, []byte(""))
+	data = marshal.AppendBytes(data, nil)
+
+	var value PresenceStruct
+	require.Equal(t, easycql.FieldAbsent, value.CQLFieldState("name"))
+
+	err := gocql.Unmarshal(typeInfo, data, &value)
+	require.NoError(t, err)
+	require.Equal(t, easycql.FieldSet, value.CQLFieldState("name"))
+	require.Equal(t, easycql.FieldNull, value.CQLFieldState("count"))
+	require.Equal(t, easycql.FieldAbsent, value.CQLFieldState("comment"))
+	require.Equal(t, easycql.FieldAbsent, value.CQLFieldState("unknown"))
+
+	// State of the previous value is reset by decoding.
+	typeInfo.Elements = []gocql.UDTField{{Name: "note", Type: gocql.NewNativeType(3, gocql.TypeVarchar, "")}}
+	err = gocql.Unmarshal(typeInfo, marshal.AppendBytes(nil, []byte("hello")), &value)
+	require.NoError(t, err)
+	require.Equal(t, easycql.FieldAbsent, value.CQLFieldState("name"))
+	require.Equal(t, easycql.FieldAbsent, value.CQLFieldState("count"))
+	require.Equal(t, easycql.FieldSet, value.CQLFieldState("comment"))
+
+	t.Run("udt methods", func(t *testing.T) {
+		t.Parallel()
+		var value PresenceStruct
+		require.NoError(t, value.UnmarshalUDT("count", gocql.NewNativeType(3, gocql.TypeInt, ""), nil))
+		require.Equal(t, easycql.FieldNull, value.CQLFieldState("count"))
+
+		// Decoding the next value resets the state of the previous one.
+		require.NoError(t, gocql.Unmarshal(typeInfo, data, &value))
+		require.NoError(t, value.UnmarshalUDT("name", gocql.NewNativeType(3, gocql.TypeVarchar, ""), []byte("a")))
+		require.NoError(t, value.UnmarshalUDT("count", gocql.NewNativeType(3, gocql.TypeInt, ""), []byte{0, 0, 0, 1}))
+		require.Equal(t, easycql.FieldSet, value.CQLFieldState("name"))
+		require.Equal(t, easycql.FieldSet, value.CQLFieldState("count"))
+		require.Equal(t, easycql.FieldAbsent, value.CQLFieldState("comment"))
+		require.NoError(t, value.UnmarshalUDT("name", gocql.NewNativeType(3, gocql.TypeVarchar, ""), nil))
+		require.Equal(t, easycql.FieldNull, value.CQLFieldState("name"))
+		require.Equal(t, easycql.FieldAbsent, value.CQLFieldState("count"))
+	})
+}
+
+func TestRequiredMarshal(t *testing.T) {
+	t.Parallel()
+
+	typeInfo := gocql.UDTTypeInfo{
+		NativeType: gocql.NewNativeType(3, gocql.TypeUDT, ""),
+		KeySpace:   "myKeyspace",
+		Name:       "RequiredUDT",
+		Elements: []gocql.UDTField{
+			{Name: "id", Type: gocql.NewNativeType(3, gocql.TypeVarchar, "")},
+			{Name: "comment", Type: gocql.NewNativeType(3, gocql.TypeVarchar, "")},
+			{Name: "tags", Type: gocql.CollectionType{
+				NativeType: gocql.NewNativeType(3, gocql.TypeList, ""),
+				Elem:       gocql.NewNativeType(3, gocql.TypeVarchar, ""),
+			}},
+			{Name: "count", Type: gocql.NewNativeType(3, gocql.TypeInt, "")},
+		},
+	}
+	comment := "hello"
+	valid := RequiredStruct{ID: "a", Comment: &comment, Tags: []string{}}
+
+	_, err := gocql.Marshal(typeInfo, valid)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name          string
+		modify        func(v *RequiredStruct)
+		expectedError string
+	}{
+		{
+			name:          "zero value",
+			modify:        func(v *RequiredStruct) { v.ID = "" },
+			expectedError: "key 'id' is required, got empty ID",
+		},
+		{
+			name:          "nil pointer",
+			modify:        func(v *RequiredStruct) { v.Comment = nil },
+			expectedError: "key 'comment' is required, got empty Comment",
+		},
+		{
+			name:          "nil slice",
+			modify:        func(v *RequiredStruct) { v.Tags = nil },
+			expectedError: "key 'tags' is required, got empty Tags",
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			value := valid
+			test.modify(&value)
+			_, err := gocql.Marshal(typeInfo, value)
+			require.EqualError(t, err, test.expectedError)
+		})
+	}
+
+	t.Run("missing element", func(t *testing.T) {
+		t.Parallel()
+
+		info := typeInfo
+		info.Elements = typeInfo.Elements[:3]
+		_, err := gocql.Marshal(info, valid)
+		require.EqualError(t, err, "key 'count' is required")
+	})
+}
+
+func TestRawField(t *testing.T) {
+	t.Parallel()
+
+	typeInfo := gocql.UDTTypeInfo{
+		NativeType: gocql.NewNativeType(3, gocql.TypeUDT, ""),
+		KeySpace:   "myKeyspace",
+		Name:       "RawUDT",
+		Elements: []gocql.UDTField{
+			{Name: "id", Type: gocql.NewNativeType(3, gocql.TypeInt, "")},
+			{Name: "payload", Type: gocql.UDTTypeInfo{
+				NativeType: gocql.NewNativeType(3, gocql.TypeUDT, ""),
+				KeySpace:   "myKeyspace",
+				Name:       "OtherUDT",
+				Elements: []gocql.UDTField{
+					{Name: "name", Type: gocql.NewNativeType(3, gocql.TypeVarchar, "")},
+				},
+			}},
+		},
+	}
+
+	payload := marshal.AppendBytes(nil, []byte("hello"))
+	var expectedData []byte
+	expectedData = marshal.AppendBytes(expectedData, []byte{0, 0, 0, 1})
+	expectedData = marshal.AppendBytes(expectedData, payload)
+
+	var unmarshaled RawStruct
+	err := gocql.Unmarshal(typeInfo, expectedData, &unmarshaled)
+	require.NoError(t, err)
+	require.Equal(t, RawStruct{ID: 1, Payload: payload}, unmarshaled)
+
+	// The raw value must not alias the decoded data.
+	expectedData[len(expectedData)-1] = 'x'
+	require.Equal(t, payload, unmarshaled.Payload)
+	expectedData[len(expectedData)-1] = 'o'
+
+	data, err := gocql.Marshal(typeInfo, unmarshaled)
+	require.NoError(t, err)
+	require.Equal(t, expectedData, data)
+
+	var nullData []byte
+	nullData = marshal.AppendBytes(nullData, []byte{0, 0, 0, 1})
+	nullData = marshal.AppendBytes(nullData, nil)
+
+	unmarshaled = RawStruct{}
+	err = gocql.Unmarshal(typeInfo, nullData, &unmarshaled)
+	require.NoError(t, err)
+	require.Nil(t, unmarshaled.Payload)
+
+	data, err = gocql.Marshal(typeInfo, unmarshaled)
+	require.NoError(t, err)
+	require.Equal(t, nullData, data)
+}
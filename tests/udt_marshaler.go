@@ -0,0 +1,32 @@
+package tests
+
+import (
+	"github.com/gocql/gocql"
+)
+
+// LegacyAddress implements only gocql.UDTMarshaler and gocql.UDTUnmarshaler.
+// This file is intentionally not processed by easycql.
+type LegacyAddress struct {
+	Street string
+	City   string
+}
+
+func (a LegacyAddress) MarshalUDT(name string, info gocql.TypeInfo) ([]byte, error) {
+	switch name {
+	case "street":
+		return gocql.Marshal(info, a.Street)
+	case "city":
+		return gocql.Marshal(info, a.City)
+	}
+	return nil, nil
+}
+
+func (a *LegacyAddress) UnmarshalUDT(name string, info gocql.TypeInfo, data []byte) error {
+	switch name {
+	case "street":
+		return gocql.Unmarshal(info, data, &a.Street)
+	case "city":
+		return gocql.Unmarshal(info, data, &a.City)
+	}
+	return nil
+}
@@ -0,0 +1,29 @@
+package tests
+
+import "github.com/kiwicom/easycql"
+
+type UDTMethodsStruct struct {
+	Name  string `easycql:"name"`
+	Count int    `easycql:"count"`
+}
+
+type UDTMethodsNotNullStruct struct {
+	Name string `easycql:"name,notnull"`
+}
+
+type UnknownCaptureStruct struct {
+	Name    string            `easycql:"name"`
+	Unknown map[string][]byte `easycql:",unknown"`
+}
+
+type UnknownErrorStruct struct {
+	_    struct{} `easycql:",unknown=error"`
+	Name string   `easycql:"name"`
+}
+
+type PresenceStruct struct {
+	Name     string                `easycql:"name"`
+	Count    int                   `easycql:"count"`
+	Comment  string                `easycql:"comment|note"`
+	Presence easycql.FieldPresence `easycql:",presence"`
+}